@@ -0,0 +1,54 @@
+// Command reputation rebuilds every user's reputation total from the
+// reputation ledger.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/setup"
+)
+
+type config struct {
+	Database database.Config
+}
+
+func (c *config) DatabaseConfig() *database.Config {
+	return &c.Database
+}
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger.MustInit()
+	defer logger.Flush()
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+}
+
+func realMain(ctx context.Context) error {
+	var cfg config
+	env, err := setup.Setup(ctx, &cfg)
+	if err != nil {
+		return fmt.Errorf("setup.Setup: %w", err)
+	}
+	defer env.Close(ctx)
+
+	updated, err := repos.NewReputationDB(env.Database()).Recompute(ctx)
+	if err != nil {
+		return fmt.Errorf("recompute: %w", err)
+	}
+
+	logger.Infof("recomputed reputation, %d totals changed", updated)
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"syscall"
 
 	"goquizbox/internal/app"
+	"goquizbox/internal/badges"
+	"goquizbox/internal/feeds"
 	"goquizbox/internal/logger"
 	"goquizbox/internal/server"
 	"goquizbox/internal/setup"
@@ -48,6 +50,15 @@ func realMain(ctx context.Context) error {
 		return fmt.Errorf("goquizbox.NewServer: %w", err)
 	}
 
+	go badges.NewEngine(env.Database()).Run(ctx, config.BadgeSweepInterval)
+	go feeds.NewScorer(env.Database()).Run(ctx, config.FeedScoreInterval)
+	go appServer.Views().Run(ctx, config.ViewFlushInterval)
+	defer func() {
+		if err := appServer.Views().Flush(context.Background()); err != nil {
+			logger.Errorf("failed to flush question views: %v", err)
+		}
+	}()
+
 	srv, err := server.New(config.Port)
 	if err != nil {
 		return fmt.Errorf("server.New: %w", err)
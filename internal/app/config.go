@@ -1,20 +1,108 @@
 package app
 
 import (
+	"fmt"
+	"time"
+
 	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/mailer"
+	"goquizbox/internal/middleware"
+	"goquizbox/internal/oidc"
 	"goquizbox/internal/setup"
+	"goquizbox/internal/web/auth"
 )
 
 var (
 	_ setup.DatabaseConfigProvider = (*Config)(nil)
+	_ setup.MailerConfigProvider   = (*Config)(nil)
 )
 
 type Config struct {
 	Database    database.Config
+	Mailer      mailer.Config
+	JWT         auth.JWTConfig
 	Environment string `env:"ENV, default=local"`
 	Port        string `env:"PORT, default=8090"`
+	Privileges  auth.PrivilegeConfig
+
+	BadgeSweepInterval time.Duration `env:"BADGE_SWEEP_INTERVAL, default=10m"`
+	FeedScoreInterval  time.Duration `env:"FEED_SCORE_INTERVAL, default=5m"`
+	CloseVotesRequired int           `env:"CLOSE_VOTES_REQUIRED, default=3"`
+
+	ViewDedupWindow   time.Duration `env:"VIEW_DEDUP_WINDOW, default=1h"`
+	ViewFlushInterval time.Duration `env:"VIEW_FLUSH_INTERVAL, default=30s"`
+	// ViewDedupMaxViewers bounds the memory used to remember recent viewers.
+	ViewDedupMaxViewers int `env:"VIEW_DEDUP_MAX_VIEWERS, default=100000"`
+
+	EmailVerificationTTL time.Duration `env:"EMAIL_VERIFICATION_TTL, default=24h"`
+	EmailResendCooldown  time.Duration `env:"EMAIL_RESEND_COOLDOWN, default=2m"`
+	PasswordResetTTL     time.Duration `env:"PASSWORD_RESET_TTL, default=1h"`
+
+	// EmailVerificationAttempts is how many wrong codes invalidate the one
+	// sent; a new one has to be requested after that.
+	EmailVerificationAttempts int `env:"EMAIL_VERIFICATION_ATTEMPTS, default=5"`
+
+	LoginAttemptWindow      time.Duration `env:"LOGIN_ATTEMPT_WINDOW, default=24h"`
+	LoginLockoutThreshold   int           `env:"LOGIN_LOCKOUT_THRESHOLD, default=5"`
+	LoginIPLockoutThreshold int           `env:"LOGIN_IP_LOCKOUT_THRESHOLD, default=20"`
+	LoginLockoutBase        time.Duration `env:"LOGIN_LOCKOUT_BASE, default=1m"`
+	LoginLockoutMax         time.Duration `env:"LOGIN_LOCKOUT_MAX, default=1h"`
+
+	TwoFactorIssuer   string        `env:"TWO_FACTOR_ISSUER, default=Quizbox"`
+	LoginChallengeTTL time.Duration `env:"LOGIN_CHALLENGE_TTL, default=5m"`
+
+	// OIDCProviders is a JSON array of oidc.ProviderConfig.
+	OIDCProviders oidc.Providers `env:"OIDC_PROVIDERS"`
+	OIDCLoginTTL  time.Duration  `env:"OIDC_LOGIN_TTL, default=10m"`
+
+	// RateLimitStore is "memory" for a single instance or "postgres" to
+	// share limits between instances. Limits are "requests/period[,burst]".
+	RateLimitStore string           `env:"RATE_LIMIT_STORE, default=memory"`
+	RateLimitAuth  middleware.Limit `env:"RATE_LIMIT_AUTH, default=10/1m"`
+	RateLimitWrite middleware.Limit `env:"RATE_LIMIT_WRITE, default=30/1m,10"`
+
+	// TrustedProxies are the addresses or CIDRs of the proxies in front of
+	// the server. X-Forwarded-For is only believed when it was set by one of
+	// them, so client IPs cannot be spoofed by the client.
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+
+	// CursorSigningKey signs pagination cursors. Every instance needs the
+	// same key for cursors to work across them.
+	CursorSigningKey string `env:"CURSOR_SIGNING_KEY, required"`
+}
+
+// validateIntervals rejects background job intervals that cannot be
+// scheduled.
+func (c *Config) validateIntervals() error {
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"BADGE_SWEEP_INTERVAL", c.BadgeSweepInterval},
+		{"FEED_SCORE_INTERVAL", c.FeedScoreInterval},
+		{"VIEW_FLUSH_INTERVAL", c.ViewFlushInterval},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("%v must be positive, got %v", interval.name, interval.value)
+		}
+	}
+	return nil
+}
+
+func (c *Config) AccountLoginBackoff() entities.LoginBackoff {
+	return entities.LoginBackoff{Threshold: c.LoginLockoutThreshold, Base: c.LoginLockoutBase, Max: c.LoginLockoutMax}
+}
+
+func (c *Config) IPLoginBackoff() entities.LoginBackoff {
+	return entities.LoginBackoff{Threshold: c.LoginIPLockoutThreshold, Base: c.LoginLockoutBase, Max: c.LoginLockoutMax}
 }
 
 func (c *Config) DatabaseConfig() *database.Config {
 	return &c.Database
 }
+
+func (c *Config) MailerConfig() *mailer.Config {
+	return &c.Mailer
+}
@@ -0,0 +1,269 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	adminUserStatusFormData struct {
+		Status string `json:"status" form:"status" binding:"required"`
+	}
+
+	adminUserRoleFormData struct {
+		Role string `json:"role" form:"role" binding:"required"`
+	}
+)
+
+// HandleAdminSetUserStatus suspends or reinstates a user. Moderators may
+// only act on plain users.
+func (s *Server) HandleAdminSetUserStatus() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form adminUserStatusFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		status := entities.UserStatus(form.Status)
+		if status != entities.UserStatusActive && status != entities.UserStatusInactive {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("status must be '%v' or '%v'", entities.UserStatusActive, entities.UserStatusInactive),
+			})
+			return
+		}
+
+		user, ok := s.managedUserFromParams(c)
+		if !ok {
+			return
+		}
+
+		if err := repos.NewUserDB(s.env.Database()).SetStatus(ctx, user.ID, status); err != nil {
+			logger.Errorf("failed to set status of user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not update user status",
+			})
+			return
+		}
+
+		user.Status = status
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    user,
+		})
+	}
+}
+
+func (s *Server) HandleAdminSetUserRole() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form adminUserRoleFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		role := entities.UserRole(form.Role)
+		if !role.IsValid() {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("unknown role %q", form.Role),
+			})
+			return
+		}
+
+		user, ok := s.managedUserFromParams(c)
+		if !ok {
+			return
+		}
+
+		if err := repos.NewUserDB(s.env.Database()).SetRole(ctx, user.ID, role); err != nil {
+			logger.Errorf("failed to set role of user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not update user role",
+			})
+			return
+		}
+
+		user.Role = role
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    user,
+		})
+	}
+}
+
+// HandleAdminDeletePost removes any question or answer regardless of who
+// wrote it.
+func (s *Server) HandleAdminDeletePost(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		kindIDStr := c.Param("id")
+		kindID, err := strconv.ParseInt(kindIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", kindIDStr),
+			})
+			return
+		}
+
+		ownerID, err := s.postOwnerID(ctx, kind, kindID)
+		if err != nil {
+			logger.Errorf("failed to get %v by id %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not get %v", kind),
+			})
+			return
+		}
+
+		if ownerID == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("%v not found", kind),
+			})
+			return
+		}
+
+		switch kind {
+		case entities.PostKindQuestion:
+			err = repos.NewQuestionDB(s.env.Database()).Delete(ctx, kindID)
+		case entities.PostKindAnswer:
+			err = repos.NewAnswerDB(s.env.Database()).Delete(ctx, kindID)
+		}
+
+		if err != nil {
+			logger.Errorf("failed to delete %v %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not delete %v", kind),
+			})
+			return
+		}
+
+		logger.Infof("user %v deleted %v %v by user %v", ctxhelper.UserID(ctx), kind, kindID, *ownerID)
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+func (s *Server) HandleAdminDeleteComment() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		commentIDStr := c.Param("id")
+		commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", commentIDStr),
+			})
+			return
+		}
+
+		db := repos.NewCommentDB(s.env.Database())
+		comment, err := db.ByID(ctx, commentID)
+		if err != nil {
+			logger.Errorf("failed to get comment by id %v: %v", commentID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get comment",
+			})
+			return
+		}
+
+		if comment == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "comment not found",
+			})
+			return
+		}
+
+		if err := db.Delete(ctx, comment.ID); err != nil {
+			logger.Errorf("failed to delete comment %v: %v", comment.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not delete comment",
+			})
+			return
+		}
+
+		logger.Infof("user %v deleted comment %v by user %v", ctxhelper.UserID(ctx), comment.ID, comment.UserID)
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// managedUserFromParams loads the user in the 'id' path param and checks
+// the caller outranks them, writing an error response when not.
+func (s *Server) managedUserFromParams(c *gin.Context) (*entities.User, bool) {
+	ctx := c.Request.Context()
+
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'id' param=[%v]", userIDStr),
+		})
+		return nil, false
+	}
+
+	user, err := repos.NewUserDB(s.env.Database()).GetByID(ctx, userID)
+	if err != nil {
+		logger.Errorf("failed to get user by id %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get user",
+		})
+		return nil, false
+	}
+
+	if user == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": "user not found",
+		})
+		return nil, false
+	}
+
+	tokenInfo := ctxhelper.TokenInfo(ctx)
+	if user.ID == tokenInfo.UserID {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"message": "you cannot change your own account here",
+		})
+		return nil, false
+	}
+
+	if !tokenInfo.HasRole(entities.UserRoleAdmin) && user.Role.AtLeast(entities.UserRoleModerator) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"message": "only admins can change moderators and admins",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
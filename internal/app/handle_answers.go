@@ -0,0 +1,166 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+type (
+	answerUpdateFormData struct {
+		Body    string `json:"body" form:"body" binding:"required"`
+		Summary string `json:"summary" form:"summary"`
+	}
+)
+
+func (s *Server) HandleApiUpdateAnswer() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		answerIDStr := c.Param("id")
+		answerID, err := strconv.ParseInt(answerIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", answerIDStr),
+			})
+			return
+		}
+
+		var form answerUpdateFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		db := repos.NewAnswerDB(s.env.Database())
+		answer, err := db.ByID(ctx, answerID)
+		if err != nil {
+			logger.Errorf("failed to get answer by id %v: %v", answerID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get answer",
+			})
+			return
+		}
+
+		if answer == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "answer not found",
+			})
+			return
+		}
+
+		if answer.UserID != ctxhelper.UserID(ctx) && !auth.PrivilegeGranted(c, entities.PrivilegeEditOthers) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "you can only edit your own answers",
+			})
+			return
+		}
+
+		answer.Body = form.Body
+		answer.UpdatedAt = null.TimeFrom(time.Now())
+
+		if errors := answer.Validate(); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not update answer: %v", strings.Join(errors, ",")),
+			})
+			return
+		}
+
+		if _, err := db.Edit(ctx, answer, ctxhelper.UserID(ctx), form.Summary); err != nil {
+			logger.Errorf("failed to update answer %v: %v", answerID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error updating the answer",
+			})
+			return
+		}
+
+		if err := s.attachAnswerVotes(ctx, answer); err != nil {
+			logger.Errorf("failed to get votes for answer %v: %v", answerID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get votes for answer",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    answer,
+		})
+	}
+}
+
+func (s *Server) HandleApiDeleteAnswer() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		answerIDStr := c.Param("id")
+		answerID, err := strconv.ParseInt(answerIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", answerIDStr),
+			})
+			return
+		}
+
+		db := repos.NewAnswerDB(s.env.Database())
+		answer, err := db.ByID(ctx, answerID)
+		if err != nil {
+			logger.Errorf("failed to get answer by id %v: %v", answerID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get answer",
+			})
+			return
+		}
+
+		if answer == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "answer not found",
+			})
+			return
+		}
+
+		if answer.UserID != ctxhelper.UserID(ctx) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "you can only delete your own answers",
+			})
+			return
+		}
+
+		if err := db.Delete(ctx, answer.ID); err != nil {
+			logger.Errorf("failed to delete answer %v: %v", answerID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not delete answer",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
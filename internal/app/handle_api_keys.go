@@ -0,0 +1,244 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/ctxhelper"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+const apiKeySecretSize = 30
+
+type (
+	apiKeyFormData struct {
+		Name  string `json:"name" form:"name" binding:"required"`
+		Scope string `json:"scope" form:"scope"`
+	}
+
+	apiKeyUpdateFormData struct {
+		Name string `json:"name" form:"name" binding:"required"`
+	}
+)
+
+// HandleApiCreateApiKey issues a new API key. The secret is only ever
+// returned here, it cannot be recovered later.
+func (s *Server) HandleApiCreateApiKey() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form apiKeyFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		secret, err := util.GenerateSecureToken(apiKeySecretSize)
+		if err != nil {
+			logger.Errorf("failed to generate api key secret: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error creating the api key",
+			})
+			return
+		}
+
+		secretHash, err := util.HashApiKeySecret(secret)
+		if err != nil {
+			logger.Errorf("failed to hash api key secret: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error creating the api key",
+			})
+			return
+		}
+
+		key := entities.NewApiKey()
+		key.UserID = ctxhelper.UserID(ctx)
+		key.Name = strings.TrimSpace(form.Name)
+		key.Username = util.GenerateApiKeyUsername()
+		key.SecretHash = secretHash
+		key.Scope = form.Scope
+		if key.Scope == "" {
+			key.Scope = entities.ApiKeyScopeRead
+		}
+
+		if errors := key.Validate(); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not create api key: %v", strings.Join(errors, ",")),
+			})
+			return
+		}
+
+		if err := repos.NewApiKeyDB(s.env.Database()).Save(ctx, key); err != nil {
+			logger.Errorf("failed to save api key: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error creating the api key",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "store the secret now, it will not be shown again",
+			"data": map[string]interface{}{
+				"api_key": key,
+				"secret":  secret,
+				"token":   auth.FormatApiKey(key.Username, secret),
+			},
+		})
+	}
+}
+
+func (s *Server) HandleApiListApiKeys() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		filter, err := webutils.ApiKeyFilterFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "Failed to parse filter",
+			})
+			return
+		}
+
+		userID := ctxhelper.UserID(ctx)
+
+		keys, err := repos.NewApiKeyDB(s.env.Database()).ByUser(ctx, userID, filter)
+		if err != nil {
+			logger.Errorf("failed to list api keys for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list api keys",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    keys,
+		})
+	}
+}
+
+func (s *Server) HandleApiRenameApiKey() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form apiKeyUpdateFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		key, ok := s.ownApiKeyFromParams(c)
+		if !ok {
+			return
+		}
+
+		key.Name = strings.TrimSpace(form.Name)
+		key.UpdatedAt = null.TimeFrom(time.Now())
+
+		if errors := key.Validate(); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not rename api key: %v", strings.Join(errors, ",")),
+			})
+			return
+		}
+
+		if err := repos.NewApiKeyDB(s.env.Database()).Save(ctx, key); err != nil {
+			logger.Errorf("failed to rename api key %v: %v", key.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error renaming the api key",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    key,
+		})
+	}
+}
+
+func (s *Server) HandleApiRevokeApiKey() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		key, ok := s.ownApiKeyFromParams(c)
+		if !ok {
+			return
+		}
+
+		if err := repos.NewApiKeyDB(s.env.Database()).Revoke(ctx, key.ID); err != nil {
+			logger.Errorf("failed to revoke api key %v: %v", key.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error revoking the api key",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// ownApiKeyFromParams loads the caller's unrevoked key identified by the
+// 'id' path param, writing an error response when there is none.
+func (s *Server) ownApiKeyFromParams(c *gin.Context) (*entities.ApiKey, bool) {
+	ctx := c.Request.Context()
+
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.ParseInt(keyIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'id' param=[%v]", keyIDStr),
+		})
+		return nil, false
+	}
+
+	key, err := repos.NewApiKeyDB(s.env.Database()).ByID(ctx, keyID)
+	if err != nil {
+		logger.Errorf("failed to get api key by id %v: %v", keyID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get api key",
+		})
+		return nil, false
+	}
+
+	if key == nil || key.IsRevoked() || key.UserID != ctxhelper.UserID(ctx) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": "api key not found",
+		})
+		return nil, false
+	}
+
+	return key, true
+}
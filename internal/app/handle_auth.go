@@ -1,8 +1,11 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -53,8 +56,15 @@ func (s *Server) HandleLogin(sessionAuthenticator auth.SessionAuthenticator) fun
 			return
 		}
 
+		email := strings.ToLower(strings.TrimSpace(form.Email))
+
+		emailFailures, ipFailures, ok := s.checkLoginLockout(c, email)
+		if !ok {
+			return
+		}
+
 		db := repos.NewUserDB(s.env.Database())
-		theUser, err := db.ByEmail(ctx, form.Email)
+		theUser, err := db.ByEmail(ctx, email)
 		if err != nil {
 			logger.Errorf("get user by email failed: %v", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -64,19 +74,18 @@ func (s *Server) HandleLogin(sessionAuthenticator auth.SessionAuthenticator) fun
 			return
 		}
 
-		if theUser == nil {
-			c.JSON(http.StatusBadRequest, map[string]interface{}{
-				"success": false,
-				"message": "invalid email provided",
-			})
-			return
+		// Check a password even for unknown emails so response times do not
+		// tell them apart from registered ones.
+		passwordHash := dummyPasswordHash
+		if theUser != nil {
+			passwordHash = theUser.PasswordHash
 		}
 
-		err = util.MatchPassword(theUser.PasswordHash, form.Password)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		if err := util.MatchPassword(passwordHash, form.Password); err != nil || theUser == nil {
+			s.recordLoginFailure(c, email, theUser, emailFailures, ipFailures)
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
 				"success": false,
-				"message": "invalid password provided",
+				"message": "invalid email or password",
 			})
 			return
 		}
@@ -97,42 +106,181 @@ func (s *Server) HandleLogin(sessionAuthenticator auth.SessionAuthenticator) fun
 			return
 		}
 
-		theSession := &entities.Session{
-			IPAddress:       ctxhelper.IPAddress(ctx),
-			LastRefreshedAt: time.Now(),
-			ExpiresAt:       null.TimeFrom(time.Now().Add(time.Hour * time.Duration(3))),
-			UserAgent:       ctxhelper.UserAgent(ctx),
-			UserID:          theUser.ID,
+		// With 2FA the login only succeeds once the second factor is checked,
+		// so a known password alone does not clear the failure count.
+		if theUser.TwoFactorEnabled() {
+			s.startLoginChallenge(c, theUser)
+			return
 		}
 
-		sessionDB := repos.NewSessionDB(s.env.Database())
-		if err := sessionDB.Save(ctx, theSession); err != nil {
-			logger.Errorf("failed to insert user session: %v", err)
-			c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"success": false,
-				"message": "session could not be saved",
-			})
-			return
+		s.recordLoginAttempt(ctx, email, true)
+		s.startSession(c, sessionAuthenticator, theUser)
+	}
+}
+
+// dummyPasswordHash is checked against when the email is not registered.
+var dummyPasswordHash = util.GeneratePasswordHash("not-a-real-password")
+
+// checkLoginLockout loads the recent login failures for email and the
+// client IP and reports whether they may try to log in, writing the error
+// response when they may not.
+func (s *Server) checkLoginLockout(c *gin.Context, email string) (*entities.LoginFailures, *entities.LoginFailures, bool) {
+	ctx := c.Request.Context()
+
+	emailFailures, ipFailures, err := repos.NewLoginAttemptDB(s.env.Database()).Failures(
+		ctx, email, ctxhelper.IPAddress(ctx), time.Now().Add(-s.config.LoginAttemptWindow),
+	)
+	if err != nil {
+		logger.Errorf("failed to get login failures: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "encountered an error logging in",
+		})
+		return nil, nil, false
+	}
+
+	if lockedUntil := s.loginLockedUntil(emailFailures, ipFailures); time.Now().Before(lockedUntil) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(lockedUntil).Seconds()))))
+		c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+			"success": false,
+			"message": "too many failed login attempts, try again later",
+		})
+		return nil, nil, false
+	}
+
+	return emailFailures, ipFailures, true
+}
+
+// checkCurrentPassword checks a password a signed in user confirms an
+// action with. Wrong passwords count as failed logins of the account, so a
+// stolen session cannot be used to guess the password any faster than the
+// login form. It writes the error response, using message for a wrong
+// password, and reports whether the password matched.
+func (s *Server) checkCurrentPassword(c *gin.Context, user *entities.User, password, message string) bool {
+	email := strings.ToLower(user.Email)
+
+	emailFailures, ipFailures, allowed := s.checkLoginLockout(c, email)
+	if !allowed {
+		return false
+	}
+
+	if err := util.MatchPassword(user.PasswordHash, password); err != nil {
+		s.recordLoginFailure(c, email, user, emailFailures, ipFailures)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": message,
+		})
+		return false
+	}
+
+	return true
+}
+
+// loginLockedUntil returns when the account or IP in the failures may try
+// to log in again.
+func (s *Server) loginLockedUntil(emailFailures, ipFailures *entities.LoginFailures) time.Time {
+	lockedUntil := s.config.AccountLoginBackoff().LockedUntil(*emailFailures)
+	if ipLockedUntil := s.config.IPLoginBackoff().LockedUntil(*ipFailures); ipLockedUntil.After(lockedUntil) {
+		lockedUntil = ipLockedUntil
+	}
+	return lockedUntil
+}
+
+func (s *Server) recordLoginAttempt(ctx context.Context, email string, success bool) {
+	attempt := entities.NewLoginAttempt()
+	attempt.Email = email
+	attempt.IPAddress = ctxhelper.IPAddress(ctx)
+	attempt.Success = success
+
+	if err := repos.NewLoginAttemptDB(s.env.Database()).Save(ctx, attempt); err != nil {
+		logger.Errorf("failed to record login attempt: %v", err)
+	}
+}
+
+// recordLoginFailure stores a failed attempt and writes an audit log entry
+// when it starts a lockout of the account or the IP.
+func (s *Server) recordLoginFailure(
+	c *gin.Context,
+	email string,
+	user *entities.User,
+	emailFailures, ipFailures *entities.LoginFailures,
+) {
+	ctx := c.Request.Context()
+	now := time.Now()
+
+	s.recordLoginAttempt(ctx, email, false)
+
+	lockouts := map[string]time.Time{
+		entities.AuditActionLoginLockout: s.config.AccountLoginBackoff().LockedUntil(
+			entities.LoginFailures{Count: emailFailures.Count + 1, LastFailure: now},
+		),
+		entities.AuditActionIPLoginLockout: s.config.IPLoginBackoff().LockedUntil(
+			entities.LoginFailures{Count: ipFailures.Count + 1, LastFailure: now},
+		),
+	}
+
+	for action, lockedUntil := range lockouts {
+		if lockedUntil.IsZero() {
+			continue
 		}
 
-		tokenValue, err := sessionAuthenticator.SetUserSessionInResponse(c.Writer, theUser, theSession)
-		if err != nil {
-			logger.Errorf("failed to set session in response: %v", err)
-			c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"success": false,
-				"message": "session could not set in response",
-			})
-			return
+		entry := entities.NewAuditLog()
+		entry.Action = action
+		entry.IPAddress = ctxhelper.IPAddress(ctx)
+		entry.UserAgent = ctxhelper.UserAgent(ctx)
+		entry.Details["email"] = email
+		entry.Details["locked_until"] = lockedUntil
+		entry.Details["email_failures"] = emailFailures.Count + 1
+		entry.Details["ip_failures"] = ipFailures.Count + 1
+		if user != nil {
+			entry.UserID = null.IntFrom(user.ID)
+		}
+
+		if err := repos.NewAuditLogDB(s.env.Database()).Save(ctx, entry); err != nil {
+			logger.Errorf("failed to record %v audit log: %v", action, err)
 		}
+	}
+}
 
-		c.SetCookie("auth-token", tokenValue, 60*60, "/", "localhost", false, true)
+// startSession logs the user in, writing the session token to the response.
+func (s *Server) startSession(c *gin.Context, sessionAuthenticator auth.SessionAuthenticator, theUser *entities.User) {
+	ctx := c.Request.Context()
 
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"user":    theUser,
-			"token":   tokenValue,
+	theSession := &entities.Session{
+		IPAddress:       ctxhelper.IPAddress(ctx),
+		LastRefreshedAt: time.Now(),
+		ExpiresAt:       null.TimeFrom(time.Now().Add(auth.SessionLifetime)),
+		UserAgent:       ctxhelper.UserAgent(ctx),
+		UserID:          theUser.ID,
+	}
+
+	sessionDB := repos.NewSessionDB(s.env.Database())
+	if err := sessionDB.Save(ctx, theSession); err != nil {
+		logger.Errorf("failed to insert user session: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "session could not be saved",
 		})
+		return
 	}
+
+	tokenValue, err := sessionAuthenticator.SetUserSessionInResponse(c.Writer, theUser, theSession)
+	if err != nil {
+		logger.Errorf("failed to set session in response: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "session could not set in response",
+		})
+		return
+	}
+
+	c.SetCookie("auth-token", tokenValue, 60*60, "/", "localhost", false, true)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"user":    theUser,
+		"token":   tokenValue,
+	})
 }
 
 func (s *Server) HandleRegister() func(c *gin.Context) {
@@ -154,7 +302,7 @@ func (s *Server) HandleRegister() func(c *gin.Context) {
 			Email:           strings.ToLower(form.Email),
 			FirstName:       cases.Title(language.English, cases.Compact).String(form.FirstName),
 			LastName:        cases.Title(language.English, cases.Compact).String(form.LastName),
-			Status:          "inactive",
+			Status:          entities.UserStatusUnverified,
 			EmailVerified:   false,
 			Password:        form.Password,
 			PasswordConfirm: form.PasswordConfirm,
@@ -198,6 +346,10 @@ func (s *Server) HandleRegister() func(c *gin.Context) {
 			return
 		}
 
+		if err := s.sendActivationEmail(ctx, newUser); err != nil {
+			logger.Errorf("failed to send activation email to user %v: %v", newUser.ID, err)
+		}
+
 		c.JSON(http.StatusCreated, gin.H{
 			"success": true,
 			"message": "check your email for activation code",
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"goquizbox/internal/badges"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) HandleListBadges() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		counts, err := repos.NewBadgeDB(s.env.Database()).HolderCounts(ctx)
+		if err != nil {
+			logger.Errorf("failed to count badge holders: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list badges",
+			})
+			return
+		}
+
+		all := badges.All()
+		for _, badge := range all {
+			badge.Holders = counts[badge.Slug]
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    all,
+		})
+	}
+}
+
+func (s *Server) HandleListBadgeHolders() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		slug := c.Param("slug")
+		badge := badges.Lookup(slug)
+		if badge == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("badge %v not found", slug),
+			})
+			return
+		}
+
+		filter, err := webutils.FilterFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "Failed to parse pagination",
+			})
+			return
+		}
+
+		db := repos.NewBadgeDB(s.env.Database())
+		holders, err := db.Holders(ctx, slug, filter)
+		if err != nil {
+			logger.Errorf("failed to list holders of badge %v: %v", slug, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list badge holders",
+			})
+			return
+		}
+
+		count, err := db.CountHolders(ctx, slug)
+		if err != nil {
+			logger.Errorf("failed to count holders of badge %v: %v", slug, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not count badge holders",
+			})
+			return
+		}
+
+		describeUserBadges(holders)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"badge":      badge,
+				"holders":    holders,
+				"pagination": entities.NewPagination(*count, filter.Page, filter.Per),
+			},
+		})
+	}
+}
+
+func (s *Server) HandleListUserBadges() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		userIDStr := c.Param("id")
+		userID, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", userIDStr),
+			})
+			return
+		}
+
+		userBadges, err := repos.NewBadgeDB(s.env.Database()).ByUser(ctx, userID)
+		if err != nil {
+			logger.Errorf("failed to list badges for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list user badges",
+			})
+			return
+		}
+
+		describeUserBadges(userBadges)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    userBadges,
+		})
+	}
+}
+
+// describeUserBadges fills in the name and class of each awarded badge.
+func describeUserBadges(userBadges []*entities.UserBadge) {
+	for _, userBadge := range userBadges {
+		if badge := badges.Lookup(userBadge.Badge); badge != nil {
+			userBadge.Name = badge.Name
+			userBadge.Class = badge.Class
+		}
+	}
+}
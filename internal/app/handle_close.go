@@ -0,0 +1,180 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+type (
+	closeVoteFormData struct {
+		Reason        string `json:"reason" form:"reason" binding:"required"`
+		DuplicateOfID int64  `json:"duplicate_of_id" form:"duplicate_of_id"`
+	}
+)
+
+// HandleApiCloseQuestionVote votes to close a question, closing it once
+// enough votes are in.
+func (s *Server) HandleApiCloseQuestionVote() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form closeVoteFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		vote := entities.NewCloseVote()
+		vote.Kind = entities.CloseVoteKindClose
+		vote.Reason = null.StringFrom(form.Reason)
+		if form.DuplicateOfID > 0 {
+			vote.DuplicateOfID = null.IntFrom(form.DuplicateOfID)
+		}
+
+		s.castCloseVote(c, vote)
+	}
+}
+
+// HandleApiReopenQuestionVote votes to reopen a closed question, reopening
+// it once enough votes are in.
+func (s *Server) HandleApiReopenQuestionVote() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		vote := entities.NewCloseVote()
+		vote.Kind = entities.CloseVoteKindReopen
+
+		s.castCloseVote(c, vote)
+	}
+}
+
+func (s *Server) castCloseVote(c *gin.Context, vote *entities.CloseVote) {
+	ctx := c.Request.Context()
+
+	questionIDStr := c.Param("id")
+	questionID, err := strconv.ParseInt(questionIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'id' param=[%v]", questionIDStr),
+		})
+		return
+	}
+
+	vote.QuestionID = questionID
+	vote.UserID = ctxhelper.UserID(ctx)
+
+	if errors := vote.Validate(); len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("could not save vote: %v", strings.Join(errors, ",")),
+		})
+		return
+	}
+
+	questionDB := repos.NewQuestionDB(s.env.Database())
+	question, err := questionDB.ByID(ctx, questionID)
+	if err != nil {
+		logger.Errorf("failed to get question by id %v: %v", questionID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get question",
+		})
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": "question not found",
+		})
+		return
+	}
+
+	if vote.Kind == entities.CloseVoteKindClose && question.Closed {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": "question is already closed",
+		})
+		return
+	}
+
+	if vote.Kind == entities.CloseVoteKindReopen && !question.Closed {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": "question is not closed",
+		})
+		return
+	}
+
+	if vote.DuplicateOfID.Valid {
+		original, err := questionDB.ByID(ctx, vote.DuplicateOfID.Int64)
+		if err != nil {
+			logger.Errorf("failed to get question by id %v: %v", vote.DuplicateOfID.Int64, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get duplicated question",
+			})
+			return
+		}
+
+		if original == nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("question %v to duplicate not found", vote.DuplicateOfID.Int64),
+			})
+			return
+		}
+	}
+
+	db := repos.NewCloseVoteDB(s.env.Database())
+	count, changed, err := db.Cast(ctx, vote, s.config.CloseVotesRequired)
+	if err != nil {
+		if errors.Is(err, repos.ErrAlreadyVoted) {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("you have already voted to %v this question", vote.Kind),
+			})
+			return
+		}
+
+		logger.Errorf("failed to cast %v vote on question %v: %v", vote.Kind, questionID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "encountered an error saving the vote",
+		})
+		return
+	}
+
+	if changed {
+		question, err = questionDB.ByID(ctx, questionID)
+		if err != nil {
+			logger.Errorf("failed to get question by id %v: %v", questionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get question",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": map[string]interface{}{
+			"question": question,
+			"votes":    count,
+			"required": s.config.CloseVotesRequired,
+		},
+	})
+}
@@ -0,0 +1,306 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/ctxhelper"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+type (
+	commentFormData struct {
+		Body     string `json:"body" form:"body" binding:"required"`
+		ParentID int64  `json:"parent_id" form:"parent_id"`
+	}
+
+	commentUpdateFormData struct {
+		Body string `json:"body" form:"body" binding:"required"`
+	}
+)
+
+// HandleApiListComments returns a page of top level comments on a question
+// or answer, each with its replies.
+func (s *Server) HandleApiListComments(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		kindIDStr := c.Param("id")
+		kindID, err := strconv.ParseInt(kindIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", kindIDStr),
+			})
+			return
+		}
+
+		filter, err := webutils.FilterFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "Failed to parse pagination",
+			})
+			return
+		}
+
+		db := repos.NewCommentDB(s.env.Database())
+		comments, err := db.ByPost(ctx, kind, kindID, filter)
+		if err != nil {
+			logger.Errorf("failed to list comments for %v %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list comments",
+			})
+			return
+		}
+
+		count, err := db.CountByPost(ctx, kind, kindID)
+		if err != nil {
+			logger.Errorf("failed to count comments for %v %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not count comments",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"comments":   comments,
+				"pagination": entities.NewPagination(*count, filter.Page, filter.Per),
+			},
+		})
+	}
+}
+
+// HandleApiAddComment comments on a question or answer. Setting parent_id
+// replies to a top level comment on the same post.
+func (s *Server) HandleApiAddComment(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		kindIDStr := c.Param("id")
+		kindID, err := strconv.ParseInt(kindIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", kindIDStr),
+			})
+			return
+		}
+
+		var form commentFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		ownerID, err := s.postOwnerID(ctx, kind, kindID)
+		if err != nil {
+			logger.Errorf("failed to get %v by id %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not get %v", kind),
+			})
+			return
+		}
+
+		if ownerID == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("%v not found", kind),
+			})
+			return
+		}
+
+		db := repos.NewCommentDB(s.env.Database())
+
+		comment := entities.NewComment()
+		comment.Kind = kind
+		comment.KindID = kindID
+		comment.UserID = ctxhelper.UserID(ctx)
+		comment.Body = strings.TrimSpace(form.Body)
+
+		if form.ParentID > 0 {
+			parent, err := db.ByID(ctx, form.ParentID)
+			if err != nil {
+				logger.Errorf("failed to get comment by id %v: %v", form.ParentID, err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not get parent comment",
+				})
+				return
+			}
+
+			if parent == nil || parent.Kind != kind || parent.KindID != kindID {
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": fmt.Sprintf("comment %v is not on this %v", form.ParentID, kind),
+				})
+				return
+			}
+
+			if parent.IsReply() {
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": "replies cannot be replied to",
+				})
+				return
+			}
+
+			comment.ParentID = null.IntFrom(parent.ID)
+		}
+
+		if errors := comment.Validate(); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not save comment: %v", strings.Join(errors, ",")),
+			})
+			return
+		}
+
+		if err := db.Save(ctx, comment); err != nil {
+			logger.Errorf("failed to save comment: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error saving the comment",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"data":    comment,
+		})
+	}
+}
+
+func (s *Server) HandleApiUpdateComment() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form commentUpdateFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		comment, ok := s.ownCommentFromParams(c, "edit")
+		if !ok {
+			return
+		}
+
+		comment.Body = strings.TrimSpace(form.Body)
+		comment.UpdatedAt = null.TimeFrom(time.Now())
+
+		if errors := comment.Validate(); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not update comment: %v", strings.Join(errors, ",")),
+			})
+			return
+		}
+
+		if err := repos.NewCommentDB(s.env.Database()).Save(ctx, comment); err != nil {
+			logger.Errorf("failed to update comment %v: %v", comment.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error updating the comment",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    comment,
+		})
+	}
+}
+
+func (s *Server) HandleApiDeleteComment() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		comment, ok := s.ownCommentFromParams(c, "delete")
+		if !ok {
+			return
+		}
+
+		if err := repos.NewCommentDB(s.env.Database()).Delete(ctx, comment.ID); err != nil {
+			logger.Errorf("failed to delete comment %v: %v", comment.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error deleting the comment",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "comment deleted",
+		})
+	}
+}
+
+// ownCommentFromParams loads the comment identified by the 'id' path param
+// and checks the caller wrote it, writing an error response when not.
+func (s *Server) ownCommentFromParams(c *gin.Context, action string) (*entities.Comment, bool) {
+	ctx := c.Request.Context()
+
+	commentIDStr := c.Param("id")
+	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'id' param=[%v]", commentIDStr),
+		})
+		return nil, false
+	}
+
+	comment, err := repos.NewCommentDB(s.env.Database()).ByID(ctx, commentID)
+	if err != nil {
+		logger.Errorf("failed to get comment by id %v: %v", commentID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get comment",
+		})
+		return nil, false
+	}
+
+	if comment == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": "comment not found",
+		})
+		return nil, false
+	}
+
+	if comment.UserID != ctxhelper.UserID(ctx) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("you can only %v your own comments", action),
+		})
+		return nil, false
+	}
+
+	return comment, true
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"net/http"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/ctxhelper"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListQuestionFeed lists a page of the named question feed. Feeds
+// page by number only.
+func (s *Server) HandleListQuestionFeed(feed string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		filter, err := webutils.FilterFromContext(c)
+		if err != nil {
+			logger.Errorf("Failed to parse pagination filter for %v feed: %v", feed, err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "Failed to parse pagination",
+			})
+			return
+		}
+
+		if filter.CursorMode {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "feeds do not support cursor pagination",
+			})
+			return
+		}
+
+		userID := ctxhelper.UserID(ctx)
+
+		db := repos.NewQuestionDB(s.env.Database())
+		questions, err := db.Feed(ctx, feed, userID, filter)
+		if err != nil {
+			logger.Errorf("failed to list %v feed: %v", feed, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list questions",
+			})
+			return
+		}
+
+		if err := s.attachQuestionVotes(ctx, questions...); err != nil {
+			logger.Errorf("failed to get votes for questions: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get votes for questions",
+			})
+			return
+		}
+
+		count, err := db.CountFeed(ctx, feed, userID)
+		if err != nil {
+			logger.Errorf("failed to count %v feed: %v", feed, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not count questions",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"questions":  questions,
+				"pagination": entities.NewPagination(*count, filter.Page, filter.Per),
+			},
+		})
+	}
+}
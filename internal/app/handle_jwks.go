@@ -0,0 +1,16 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleJWKS publishes the token verification keys so other services can
+// check our tokens. The body is a plain JWK Set, not the usual envelope.
+func (s *Server) HandleJWKS() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, s.jwtKeys.JWKS())
+	}
+}
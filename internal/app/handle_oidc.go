@@ -0,0 +1,415 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/oidc"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	oidcStateCookie    = "oidc-state"
+	oidcCookiePath     = "/api/v1/auth/oidc"
+	oidcStateSize      = 32
+	oidcNonceSize      = 32
+	oidcPasswordSize   = 24
+	oidcFallbackLast   = "User"
+	oidcLoginFailedMsg = "could not log in with the provider, try again"
+)
+
+// oidcProviderFromParams finds the provider named in the path, writing a
+// 404 when it is not configured.
+func (s *Server) oidcProviderFromParams(c *gin.Context) (*oidc.Provider, bool) {
+	provider, err := s.oidc.Provider(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("unknown login provider %q", c.Param("provider")),
+		})
+		return nil, false
+	}
+	return provider, true
+}
+
+// HandleOIDCLogin starts the authorization code flow by sending the user to
+// the provider. The state is also set in a cookie so the callback can only
+// be completed by the browser that started it.
+func (s *Server) HandleOIDCLogin() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		provider, ok := s.oidcProviderFromParams(c)
+		if !ok {
+			return
+		}
+
+		authURL, state, err := s.startOIDCLogin(ctx, provider, null.Int{})
+		if err != nil {
+			logger.Errorf("failed to start %v login: %v", provider.Name(), err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": oidcLoginFailedMsg,
+			})
+			return
+		}
+
+		c.SetCookie(oidcStateCookie, state, int(s.config.OIDCLoginTTL/time.Second), oidcCookiePath, "", false, true)
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// HandleApiLinkOIDC starts the flow for the signed in user to link their
+// account at the provider. It answers with the provider URL for the client
+// to send the browser to; the callback then links instead of logging in.
+func (s *Server) HandleApiLinkOIDC() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		provider, ok := s.oidcProviderFromParams(c)
+		if !ok {
+			return
+		}
+
+		authURL, state, err := s.startOIDCLogin(ctx, provider, null.IntFrom(ctxhelper.UserID(ctx)))
+		if err != nil {
+			logger.Errorf("failed to start %v link: %v", provider.Name(), err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": oidcLoginFailedMsg,
+			})
+			return
+		}
+
+		c.SetCookie(oidcStateCookie, state, int(s.config.OIDCLoginTTL/time.Second), oidcCookiePath, "", false, true)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"url": authURL,
+			},
+		})
+	}
+}
+
+// startOIDCLogin stores a fresh state, nonce and PKCE verifier and returns
+// the provider URL to send the user to along with the state. userID is set
+// when the flow links the provider account to that user.
+func (s *Server) startOIDCLogin(ctx context.Context, provider *oidc.Provider, userID null.Int) (string, string, error) {
+	state, err := util.GenerateSecureToken(oidcStateSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := util.GenerateSecureToken(oidcNonceSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	login := entities.NewOIDCLogin()
+	login.UserID = userID
+	login.Provider = provider.Name()
+	login.StateHash = util.HashToken(state)
+	login.Nonce = nonce
+	login.CodeVerifier = verifier
+	login.ExpiresAt = time.Now().Add(s.config.OIDCLoginTTL)
+
+	if err := repos.NewOIDCDB(s.env.Database()).CreateLogin(ctx, login); err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// HandleOIDCCallback finishes the flow: it checks the state, exchanges the
+// code, validates the ID token and logs in the linked user, linking or
+// creating one by verified email on first login. Flows started through
+// HandleApiLinkOIDC link the provider account instead.
+func (s *Server) HandleOIDCCallback(sessionAuthenticator auth.SessionAuthenticator) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		provider, ok := s.oidcProviderFromParams(c)
+		if !ok {
+			return
+		}
+
+		if errCode := c.Query("error"); errCode != "" {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("login was not completed at the provider: %v", errCode),
+			})
+			return
+		}
+
+		state := c.Query("state")
+		code := c.Query("code")
+		cookieState, _ := c.Cookie(oidcStateCookie)
+		c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", false, true)
+
+		if state == "" || code == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid login state, start the login again",
+			})
+			return
+		}
+
+		login, err := repos.NewOIDCDB(s.env.Database()).UseLogin(ctx, provider.Name(), util.HashToken(state))
+		if err != nil {
+			logger.Errorf("failed to use %v login: %v", provider.Name(), err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": oidcLoginFailedMsg,
+			})
+			return
+		}
+
+		if login == nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid login state, start the login again",
+			})
+			return
+		}
+
+		tokens, err := provider.Exchange(ctx, code, login.CodeVerifier)
+		if err != nil {
+			logger.Errorf("failed to exchange %v code: %v", provider.Name(), err)
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"message": oidcLoginFailedMsg,
+			})
+			return
+		}
+
+		claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, login.Nonce)
+		if err != nil {
+			logger.Errorf("failed to verify %v id token: %v", provider.Name(), err)
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"message": oidcLoginFailedMsg,
+			})
+			return
+		}
+
+		if login.UserID.Valid {
+			s.linkOIDCIdentity(c, provider, claims, login.UserID.Int64)
+			return
+		}
+
+		user, status, message := s.oidcUser(c, provider, claims)
+		if user == nil {
+			c.JSON(status, map[string]interface{}{
+				"success": false,
+				"message": message,
+			})
+			return
+		}
+
+		if !user.Status.IsActive() {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "user is inactive",
+			})
+			return
+		}
+
+		if user.TwoFactorEnabled() {
+			s.startLoginChallenge(c, user)
+			return
+		}
+
+		s.startSession(c, sessionAuthenticator, user)
+	}
+}
+
+// linkOIDCIdentity links the provider account in claims to the user who
+// started the flow.
+func (s *Server) linkOIDCIdentity(c *gin.Context, provider *oidc.Provider, claims *oidc.Claims, userID int64) {
+	ctx := c.Request.Context()
+	oidcDB := repos.NewOIDCDB(s.env.Database())
+
+	identity, err := oidcDB.IdentityBySubject(ctx, provider.Name(), claims.Subject)
+	if err != nil {
+		logger.Errorf("failed to get %v identity: %v", provider.Name(), err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": oidcLoginFailedMsg,
+		})
+		return
+	}
+
+	if identity != nil && identity.UserID != userID {
+		c.JSON(http.StatusConflict, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("this %v account is already linked to another user", provider.Name()),
+		})
+		return
+	}
+
+	if identity == nil {
+		identity = entities.NewUserIdentity()
+		identity.UserID = userID
+		identity.Provider = provider.Name()
+		identity.Subject = claims.Subject
+		identity.Email = strings.ToLower(strings.TrimSpace(claims.Email))
+
+		if err := oidcDB.LinkIdentity(ctx, identity); err != nil {
+			logger.Errorf("failed to link %v identity to user %v: %v", provider.Name(), userID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": oidcLoginFailedMsg,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("%v account linked, you can now log in with it", provider.Name()),
+	})
+}
+
+// oidcUser returns the user for the provider identity, linking it to the
+// account with the same email when both the provider and the account have
+// verified it, or registering a new account. On failure it returns the
+// status and message to respond with.
+func (s *Server) oidcUser(c *gin.Context, provider *oidc.Provider, claims *oidc.Claims) (*entities.User, int, string) {
+	ctx := c.Request.Context()
+
+	userDB := repos.NewUserDB(s.env.Database())
+	oidcDB := repos.NewOIDCDB(s.env.Database())
+
+	identity, err := oidcDB.IdentityBySubject(ctx, provider.Name(), claims.Subject)
+	if err != nil {
+		logger.Errorf("failed to get %v identity: %v", provider.Name(), err)
+		return nil, http.StatusInternalServerError, oidcLoginFailedMsg
+	}
+
+	if identity != nil {
+		user, err := userDB.GetByID(ctx, identity.UserID)
+		if err != nil || user == nil {
+			logger.Errorf("failed to get user by id %v: %v", identity.UserID, err)
+			return nil, http.StatusInternalServerError, oidcLoginFailedMsg
+		}
+		return user, 0, ""
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return nil, http.StatusForbidden, "the provider did not confirm a verified email for this account"
+	}
+
+	identity = entities.NewUserIdentity()
+	identity.Provider = provider.Name()
+	identity.Subject = claims.Subject
+	identity.Email = email
+
+	user, err := userDB.ByEmail(ctx, email)
+	if err != nil {
+		logger.Errorf("get user by email failed: %v", err)
+		return nil, http.StatusInternalServerError, oidcLoginFailedMsg
+	}
+
+	// Linking an account whose email was never verified would hand it to
+	// whoever registered that address first, so the owner has to sign in
+	// and link the provider themselves.
+	if user != nil && !user.EmailVerified {
+		return nil, http.StatusConflict, fmt.Sprintf(
+			"an account with this email exists but its email is not verified, log in with your password and link %v from your account",
+			provider.Name(),
+		)
+	}
+
+	if user != nil {
+		identity.UserID = user.ID
+		if err := oidcDB.LinkIdentity(ctx, identity); err != nil {
+			logger.Errorf("failed to link %v identity to user %v: %v", provider.Name(), user.ID, err)
+			return nil, http.StatusInternalServerError, oidcLoginFailedMsg
+		}
+
+		user, err = userDB.GetByID(ctx, user.ID)
+		if err != nil || user == nil {
+			logger.Errorf("failed to reload user after linking: %v", err)
+			return nil, http.StatusInternalServerError, oidcLoginFailedMsg
+		}
+		return user, 0, ""
+	}
+
+	// The account has no usable password until the user resets it, so the
+	// provider stays the only way in.
+	password, err := util.GenerateSecureToken(oidcPasswordSize)
+	if err != nil {
+		logger.Errorf("failed to generate password: %v", err)
+		return nil, http.StatusInternalServerError, oidcLoginFailedMsg
+	}
+
+	firstName, lastName := oidcNames(claims)
+	user = &entities.User{
+		Email:           email,
+		FirstName:       firstName,
+		LastName:        lastName,
+		Status:          entities.UserStatusActive,
+		EmailVerified:   true,
+		Password:        password,
+		PasswordConfirm: password,
+		PasswordHash:    util.GeneratePasswordHash(password),
+	}
+
+	if errors := user.Validate(); len(errors) > 0 {
+		return nil, http.StatusBadRequest, fmt.Sprintf("could not register user: %v", strings.Join(errors, ","))
+	}
+
+	if err := oidcDB.CreateUserWithIdentity(ctx, user, identity); err != nil {
+		logger.Errorf("failed to register %v user: %v", provider.Name(), err)
+		return nil, http.StatusInternalServerError, oidcLoginFailedMsg
+	}
+
+	return user, 0, ""
+}
+
+// oidcNames picks first and last names from the claims, falling back to
+// the email's local part when the provider shares no name.
+func oidcNames(claims *oidc.Claims) (string, string) {
+	first := strings.TrimSpace(claims.GivenName)
+	last := strings.TrimSpace(claims.FamilyName)
+
+	if first == "" && last == "" {
+		parts := strings.SplitN(strings.TrimSpace(claims.Name), " ", 2)
+		first = parts[0]
+		if len(parts) == 2 {
+			last = strings.TrimSpace(parts[1])
+		}
+	}
+
+	if first == "" {
+		first = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	if last == "" {
+		last = oidcFallbackLast
+	}
+
+	return first, last
+}
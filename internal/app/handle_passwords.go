@@ -0,0 +1,250 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/mailer"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+const passwordResetTokenSize = 32
+
+type (
+	forgotPasswordFormData struct {
+		Email string `json:"email" form:"email" binding:"required"`
+	}
+
+	resetPasswordFormData struct {
+		Token           string `json:"token" form:"token" binding:"required"`
+		Password        string `json:"password" form:"password" binding:"required"`
+		PasswordConfirm string `json:"password_confirm" form:"password_confirm" binding:"required"`
+	}
+
+	changePasswordFormData struct {
+		CurrentPassword string `json:"current_password" form:"current_password" binding:"required"`
+		Password        string `json:"password" form:"password" binding:"required"`
+		PasswordConfirm string `json:"password_confirm" form:"password_confirm" binding:"required"`
+	}
+)
+
+// HandleForgotPassword mails a password reset token. The response is the
+// same whether or not the email is registered.
+func (s *Server) HandleForgotPassword() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form forgotPasswordFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		user, err := repos.NewUserDB(s.env.Database()).ByEmail(ctx, strings.TrimSpace(form.Email))
+		if err != nil {
+			logger.Errorf("get user by email failed: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered error searching user by email",
+			})
+			return
+		}
+
+		if user != nil {
+			token, err := util.GenerateSecureToken(passwordResetTokenSize)
+			if err != nil {
+				logger.Errorf("failed to generate password reset token: %v", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "encountered an error creating the password reset",
+				})
+				return
+			}
+
+			reset := entities.NewPasswordReset()
+			reset.UserID = user.ID
+			reset.TokenHash = util.HashToken(token)
+			reset.IPAddress = ctxhelper.IPAddress(ctx)
+			reset.ExpiresAt = time.Now().Add(s.config.PasswordResetTTL)
+
+			if err := repos.NewPasswordResetDB(s.env.Database()).Create(ctx, reset); err != nil {
+				logger.Errorf("failed to save password reset for user %v: %v", user.ID, err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "encountered an error creating the password reset",
+				})
+				return
+			}
+
+			if err := s.env.Mailer().Send(ctx, &mailer.Message{
+				To:      user.Email,
+				Subject: "Reset your goquizbox password",
+				Body: fmt.Sprintf(
+					"Hi %s,\n\nUse this token to reset your goquizbox password: %s\n\nIt expires in %s. If you did not ask for a reset you can ignore this email.\n",
+					user.FirstName, token, s.config.PasswordResetTTL,
+				),
+			}); err != nil {
+				logger.Errorf("failed to send password reset to user %v: %v", user.ID, err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "encountered an error sending the password reset",
+				})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "if that email is registered, a password reset token has been sent",
+		})
+	}
+}
+
+func (s *Server) HandleResetPassword() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form resetPasswordFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		if message := validateNewPassword(form.Password, form.PasswordConfirm); message != "" {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": message,
+			})
+			return
+		}
+
+		db := repos.NewPasswordResetDB(s.env.Database())
+		reset, err := db.ByTokenHash(ctx, util.HashToken(strings.TrimSpace(form.Token)))
+		if err != nil {
+			logger.Errorf("failed to get password reset: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get password reset",
+			})
+			return
+		}
+
+		if reset == nil || !reset.IsUsable(time.Now()) {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid or expired password reset token",
+			})
+			return
+		}
+
+		if err := db.Use(ctx, reset, util.GeneratePasswordHash(form.Password)); err != nil {
+			if errors.Is(err, repos.ErrPasswordResetUsed) {
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": "invalid or expired password reset token",
+				})
+				return
+			}
+
+			logger.Errorf("failed to reset password for user %v: %v", reset.UserID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error resetting the password",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "password reset, you can now log in",
+		})
+	}
+}
+
+// HandleApiChangePassword sets a new password for the caller. Every other
+// session of theirs is logged out.
+func (s *Server) HandleApiChangePassword() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form changePasswordFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		tokenInfo := ctxhelper.TokenInfo(ctx)
+
+		db := repos.NewUserDB(s.env.Database())
+		user, err := db.GetByID(ctx, tokenInfo.UserID)
+		if err != nil || user == nil {
+			logger.Errorf("failed to get user by id %v: %v", tokenInfo.UserID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get user",
+			})
+			return
+		}
+
+		if !s.checkCurrentPassword(c, user, form.CurrentPassword, "current password is incorrect") {
+			return
+		}
+
+		if message := validateNewPassword(form.Password, form.PasswordConfirm); message != "" {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": message,
+			})
+			return
+		}
+
+		if err := db.ChangePassword(ctx, user.ID, util.GeneratePasswordHash(form.Password), tokenInfo.SessionID); err != nil {
+			logger.Errorf("failed to change password for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error changing the password",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "password changed, your other sessions have been logged out",
+		})
+	}
+}
+
+// validateNewPassword returns a message describing what is wrong with a new
+// password, empty if it is fine.
+func validateNewPassword(password, passwordConfirm string) string {
+	if err := util.ValidatePassword(password); err != nil {
+		return "password must be at least 8 characters with no spaces"
+	}
+
+	if err := util.CheckMatchingPasswords(password, passwordConfirm); err != nil {
+		return "password and password confirm must be the same"
+	}
+
+	return ""
+}
@@ -6,14 +6,18 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"goquizbox/internal/entities"
 	"goquizbox/internal/logger"
 	"goquizbox/internal/repos"
+	"goquizbox/internal/views"
+	"goquizbox/internal/web/auth"
 	"goquizbox/internal/web/ctxhelper"
 	"goquizbox/internal/web/webutils"
 
 	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
 )
 
 type (
@@ -24,6 +28,13 @@ type (
 		Tags   string `json:"tags" form:"tags" binding:"required"`
 	}
 
+	questionUpdateFormData struct {
+		Title   string `json:"title" form:"title" binding:"required"`
+		Body    string `json:"body" form:"body" binding:"required"`
+		Tags    string `json:"tags" form:"tags" binding:"required"`
+		Summary string `json:"summary" form:"summary"`
+	}
+
 	answerFormData struct {
 		UserID     int64  `json:"user_id" form:"user_id" binding:"required"`
 		QuestionID int64  `json:"question_id" form:"question_id" binding:"required"`
@@ -34,7 +45,13 @@ type (
 func (f *questionFormData) populateQuestion(m *entities.Question) {
 	m.Title = f.Title
 	m.Body = f.Body
-	m.Tags = f.Tags
+	m.Tags = entities.ParseTags(f.Tags)
+}
+
+func (f *questionUpdateFormData) populateQuestion(m *entities.Question) {
+	m.Title = f.Title
+	m.Body = f.Body
+	m.Tags = entities.ParseTags(f.Tags)
 }
 
 func (f *answerFormData) populateAnswer(m *entities.Answer) {
@@ -123,6 +140,40 @@ func (s *Server) HandleApiAddQuestionAnswer() func(c *gin.Context) {
 			return
 		}
 
+		question, err := repos.NewQuestionDB(s.env.Database()).ByID(ctx, newAnswer.QuestionID)
+		if err != nil {
+			logger.Errorf("failed to get question by id %v: %v", newAnswer.QuestionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get question",
+			})
+			return
+		}
+
+		if question == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "question not found",
+			})
+			return
+		}
+
+		if question.Closed {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "question is closed and no longer accepts answers",
+			})
+			return
+		}
+
+		if errors := newAnswer.Validate(); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not create answer: %v", strings.Join(errors, ",")),
+			})
+			return
+		}
+
 		db := repos.NewAnswerDB(s.env.Database())
 		if err := db.Save(ctx, newAnswer); err != nil {
 			logger.Errorf("failed to save answer: %v", err)
@@ -155,8 +206,24 @@ func (s *Server) HandleListQuestions() func(c *gin.Context) {
 			return
 		}
 
+		order, err := webutils.OrderFilterFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if !s.decodeCursor(c, filter) {
+			return
+		}
+
 		db := repos.NewQuestionDB(s.env.Database())
-		questions, err := db.List(ctx, filter)
+		questions, page, err := db.List(ctx, filter, order)
+		if listRequestError(c, err, order) {
+			return
+		}
 		if err != nil {
 			logger.Errorf("failed to list questions: %v", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -166,17 +233,31 @@ func (s *Server) HandleListQuestions() func(c *gin.Context) {
 			return
 		}
 
-		count, err := db.Count(ctx, filter)
-		if err != nil {
-			logger.Errorf("failed to count questions: %v", err)
+		if err := s.attachQuestionVotes(ctx, questions...); err != nil {
+			logger.Errorf("failed to get votes for questions: %v", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
 				"success": false,
-				"message": "could not count questions",
+				"message": "could not get votes for questions",
 			})
 			return
 		}
 
-		pagination := entities.NewPagination(*count, filter.Page, filter.Per)
+		var pagination interface{}
+		if filter.CursorMode {
+			pagination = s.cursorPagination(page, filter.Per)
+		} else {
+			count, err := db.Count(ctx, filter)
+			if err != nil {
+				logger.Errorf("failed to count questions: %v", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not count questions",
+				})
+				return
+			}
+
+			pagination = entities.NewPagination(*count, filter.Page, filter.Per)
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -213,6 +294,28 @@ func (s *Server) HandleApiGetQuestion() func(c *gin.Context) {
 			return
 		}
 
+		if question == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "question not found",
+			})
+			return
+		}
+
+		if err := s.attachQuestionVotes(ctx, question); err != nil {
+			logger.Errorf("failed to get votes for question %v: %v", questionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get votes for question",
+			})
+			return
+		}
+
+		viewer := views.Viewer(ctxhelper.UserID(ctx), ctxhelper.IPAddress(ctx), ctxhelper.UserAgent(ctx))
+		if s.views.Record(question.ID, viewer) {
+			question.ViewCount++
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"data":    question,
@@ -243,8 +346,24 @@ func (s *Server) HandleApiGetQuestionAnswers() func(c *gin.Context) {
 			return
 		}
 
+		order, err := webutils.OrderFilterFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if !s.decodeCursor(c, filter) {
+			return
+		}
+
 		answerDB := repos.NewAnswerDB(s.env.Database())
-		answers, err := answerDB.ByQuestion(ctx, questionID, filter)
+		answers, page, err := answerDB.ByQuestion(ctx, questionID, filter, order)
+		if listRequestError(c, err, order) {
+			return
+		}
 		if err != nil {
 			logger.Errorf("failed to get answers for question: %v", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -254,21 +373,297 @@ func (s *Server) HandleApiGetQuestionAnswers() func(c *gin.Context) {
 			return
 		}
 
-		count, err := answerDB.CountByQuestion(ctx, questionID, filter)
-		if err != nil {
+		if err := s.attachAnswerVotes(ctx, answers...); err != nil {
+			logger.Errorf("failed to get votes for answers: %v", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
 				"success": false,
-				"message": "could not count answers",
+				"message": "could not get votes for answers",
 			})
 			return
 		}
 
+		var pagination interface{}
+		if filter.CursorMode {
+			pagination = s.cursorPagination(page, filter.Per)
+		} else {
+			count, err := answerDB.CountByQuestion(ctx, questionID, filter)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not count answers",
+				})
+				return
+			}
+
+			pagination = entities.NewPagination(*count, filter.Page, filter.Per)
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"data": map[string]interface{}{
 				"answers":    answers,
-				"pagination": entities.NewPagination(*count, filter.Page, filter.Per),
+				"pagination": pagination,
 			},
 		})
 	}
 }
+
+func (s *Server) HandleApiUpdateQuestion() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		questionIDStr := c.Param("id")
+		questionID, err := strconv.ParseInt(questionIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", questionIDStr),
+			})
+			return
+		}
+
+		var form questionUpdateFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		db := repos.NewQuestionDB(s.env.Database())
+		question, err := db.ByID(ctx, questionID)
+		if err != nil {
+			logger.Errorf("failed to get question by id %v: %v", questionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get question",
+			})
+			return
+		}
+
+		if question == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "question not found",
+			})
+			return
+		}
+
+		if question.UserID != ctxhelper.UserID(ctx) && !auth.PrivilegeGranted(c, entities.PrivilegeEditOthers) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "you can only edit your own questions",
+			})
+			return
+		}
+
+		form.populateQuestion(question)
+		question.UpdatedAt = null.TimeFrom(time.Now())
+
+		if errors := question.Validate(); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not update question: %v", strings.Join(errors, ",")),
+			})
+			return
+		}
+
+		if _, err := db.Edit(ctx, question, ctxhelper.UserID(ctx), form.Summary); err != nil {
+			logger.Errorf("failed to update question %v: %v", questionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error updating the question",
+			})
+			return
+		}
+
+		if err := s.attachQuestionVotes(ctx, question); err != nil {
+			logger.Errorf("failed to get votes for question %v: %v", questionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get votes for question",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    question,
+		})
+	}
+}
+
+func (s *Server) HandleApiDeleteQuestion() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		questionIDStr := c.Param("id")
+		questionID, err := strconv.ParseInt(questionIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", questionIDStr),
+			})
+			return
+		}
+
+		db := repos.NewQuestionDB(s.env.Database())
+		question, err := db.ByID(ctx, questionID)
+		if err != nil {
+			logger.Errorf("failed to get question by id %v: %v", questionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get question",
+			})
+			return
+		}
+
+		if question == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "question not found",
+			})
+			return
+		}
+
+		if question.UserID != ctxhelper.UserID(ctx) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "you can only delete your own questions",
+			})
+			return
+		}
+
+		if err := db.Delete(ctx, question.ID); err != nil {
+			logger.Errorf("failed to delete question %v: %v", questionID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not delete question",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+func (s *Server) HandleApiAcceptAnswer() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		s.setAcceptedAnswer(c, true)
+	}
+}
+
+func (s *Server) HandleApiUnacceptAnswer() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		s.setAcceptedAnswer(c, false)
+	}
+}
+
+func (s *Server) setAcceptedAnswer(c *gin.Context, accept bool) {
+	ctx := c.Request.Context()
+
+	questionIDStr := c.Param("id")
+	questionID, err := strconv.ParseInt(questionIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'id' param=[%v]", questionIDStr),
+		})
+		return
+	}
+
+	answerIDStr := c.Param("answerId")
+	answerID, err := strconv.ParseInt(answerIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'answerId' param=[%v]", answerIDStr),
+		})
+		return
+	}
+
+	db := repos.NewQuestionDB(s.env.Database())
+	question, err := db.ByID(ctx, questionID)
+	if err != nil {
+		logger.Errorf("failed to get question by id %v: %v", questionID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get question",
+		})
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": "question not found",
+		})
+		return
+	}
+
+	if question.UserID != ctxhelper.UserID(ctx) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"message": "only the author of the question can accept an answer",
+		})
+		return
+	}
+
+	answer, err := repos.NewAnswerDB(s.env.Database()).ByID(ctx, answerID)
+	if err != nil {
+		logger.Errorf("failed to get answer by id %v: %v", answerID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get answer",
+		})
+		return
+	}
+
+	if answer == nil || answer.QuestionID != question.ID {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": "answer not found for question",
+		})
+		return
+	}
+
+	acceptedAnswerID := null.IntFrom(answer.ID)
+	if !accept {
+		if question.AcceptedAnswerID.Int64 != answer.ID {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "that answer is not the accepted answer",
+			})
+			return
+		}
+		acceptedAnswerID = null.Int{}
+	}
+
+	if err := db.SetAcceptedAnswer(ctx, question.ID, acceptedAnswerID); err != nil {
+		logger.Errorf("failed to set accepted answer on question %v: %v", question.ID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not update the accepted answer",
+		})
+		return
+	}
+
+	question.AcceptedAnswerID = acceptedAnswerID
+	question.Resolved = acceptedAnswerID.Valid
+
+	if err := s.attachQuestionVotes(ctx, question); err != nil {
+		logger.Errorf("failed to get votes for question %v: %v", questionID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get votes for question",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    question,
+	})
+}
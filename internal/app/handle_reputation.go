@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetUserReputation returns a user's reputation total along with a
+// page of the ledger entries that make it up.
+func (s *Server) HandleGetUserReputation() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		userIDStr := c.Param("id")
+		userID, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", userIDStr),
+			})
+			return
+		}
+
+		filter, err := webutils.FilterFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "Failed to parse pagination",
+			})
+			return
+		}
+
+		user, err := repos.NewUserDB(s.env.Database()).GetByID(ctx, userID)
+		if err != nil {
+			logger.Errorf("failed to get user by id %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get user",
+			})
+			return
+		}
+
+		if user == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "user not found",
+			})
+			return
+		}
+
+		if !s.decodeCursor(c, filter) {
+			return
+		}
+
+		db := repos.NewReputationDB(s.env.Database())
+		events, page, err := db.ByUser(ctx, userID, filter)
+		if listRequestError(c, err, nil) {
+			return
+		}
+		if err != nil {
+			logger.Errorf("failed to list reputation for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list reputation history",
+			})
+			return
+		}
+
+		var pagination interface{}
+		if filter.CursorMode {
+			pagination = s.cursorPagination(page, filter.Per)
+		} else {
+			count, err := db.CountByUser(ctx, userID)
+			if err != nil {
+				logger.Errorf("failed to count reputation for user %v: %v", userID, err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not count reputation history",
+				})
+				return
+			}
+
+			pagination = entities.NewPagination(*count, filter.Page, filter.Per)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"reputation": user.Reputation,
+				"events":     events,
+				"pagination": pagination,
+			},
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/ctxhelper"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+func (s *Server) HandleApiListRevisions(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		kindIDStr := c.Param("id")
+		kindID, err := strconv.ParseInt(kindIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", kindIDStr),
+			})
+			return
+		}
+
+		filter, err := webutils.FilterFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "Failed to parse pagination",
+			})
+			return
+		}
+
+		db := repos.NewRevisionDB(s.env.Database())
+		revisions, err := db.ByPost(ctx, kind, kindID, filter)
+		if err != nil {
+			logger.Errorf("failed to list revisions for %v %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list revisions",
+			})
+			return
+		}
+
+		count, err := db.CountByPost(ctx, kind, kindID)
+		if err != nil {
+			logger.Errorf("failed to count revisions for %v %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not count revisions",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"revisions":  revisions,
+				"pagination": entities.NewPagination(*count, filter.Page, filter.Per),
+			},
+		})
+	}
+}
+
+func (s *Server) HandleApiGetRevision(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		revision, ok := s.revisionFromParams(c, kind)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    revision,
+		})
+	}
+}
+
+// HandleApiDiffRevisions compares the revision in the path against the one
+// given by the 'against' query param, defaulting to the revision before it.
+func (s *Server) HandleApiDiffRevisions(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		revision, ok := s.revisionFromParams(c, kind)
+		if !ok {
+			return
+		}
+
+		againstNum := revision.Revision - 1
+		if againstStr := strings.TrimSpace(c.Query("against")); againstStr != "" {
+			var err error
+			againstNum, err = strconv.Atoi(againstStr)
+			if err != nil || againstNum < 1 {
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": fmt.Sprintf("failed to parse 'against' query=[%v]", againstStr),
+				})
+				return
+			}
+		}
+
+		against := entities.NewRevision()
+		if againstNum > 0 {
+			var err error
+			against, err = repos.NewRevisionDB(s.env.Database()).ByRevision(ctx, kind, revision.KindID, againstNum)
+			if err != nil {
+				logger.Errorf("failed to get revision %v of %v %v: %v", againstNum, kind, revision.KindID, err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not get revision",
+				})
+				return
+			}
+
+			if against == nil {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"success": false,
+					"message": fmt.Sprintf("revision %v not found", againstNum),
+				})
+				return
+			}
+		}
+
+		diff := map[string]interface{}{
+			"from": againstNum,
+			"to":   revision.Revision,
+			"body": util.DiffLines(against.Body, revision.Body),
+		}
+		if kind == entities.PostKindQuestion {
+			diff["title"] = util.DiffLines(against.Title.String, revision.Title.String)
+			diff["tags"] = util.DiffLines(against.Tags.String, revision.Tags.String)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    diff,
+		})
+	}
+}
+
+// HandleApiRollbackRevision restores the content of an earlier revision. The
+// rollback is itself recorded as a new revision.
+func (s *Server) HandleApiRollbackRevision(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		revision, ok := s.revisionFromParams(c, kind)
+		if !ok {
+			return
+		}
+
+		ownerID, err := s.postOwnerID(ctx, kind, revision.KindID)
+		if err != nil {
+			logger.Errorf("failed to get %v by id %v: %v", kind, revision.KindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not get %v", kind),
+			})
+			return
+		}
+
+		if ownerID == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("%v not found", kind),
+			})
+			return
+		}
+
+		userID := ctxhelper.UserID(ctx)
+		if *ownerID != userID && !auth.PrivilegeGranted(c, entities.PrivilegeEditOthers) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("you can only roll back your own %v", kind),
+			})
+			return
+		}
+
+		summary := fmt.Sprintf("Rolled back to revision %d", revision.Revision)
+
+		var data interface{}
+		switch kind {
+		case entities.PostKindQuestion:
+			db := repos.NewQuestionDB(s.env.Database())
+			var question *entities.Question
+			question, err = db.ByID(ctx, revision.KindID)
+			if err == nil && question == nil {
+				err = repos.ErrPostNotFound
+			}
+			if err == nil {
+				question.Title = revision.Title.String
+				question.Body = revision.Body
+				question.Tags = entities.ParseTags(revision.Tags.String)
+				question.UpdatedAt = null.TimeFrom(time.Now())
+				_, err = db.Edit(ctx, question, userID, summary)
+			}
+			data = question
+		case entities.PostKindAnswer:
+			db := repos.NewAnswerDB(s.env.Database())
+			var answer *entities.Answer
+			answer, err = db.ByID(ctx, revision.KindID)
+			if err == nil && answer == nil {
+				err = repos.ErrPostNotFound
+			}
+			if err == nil {
+				answer.Body = revision.Body
+				answer.UpdatedAt = null.TimeFrom(time.Now())
+				_, err = db.Edit(ctx, answer, userID, summary)
+			}
+			data = answer
+		}
+
+		if errors.Is(err, repos.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("%v not found", kind),
+			})
+			return
+		}
+
+		if err != nil {
+			logger.Errorf("failed to roll back %v %v to revision %v: %v", kind, revision.KindID, revision.Revision, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not roll back revision",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    data,
+		})
+	}
+}
+
+// revisionFromParams loads the revision identified by the 'id' and
+// 'revision' path params, writing an error response when it cannot.
+func (s *Server) revisionFromParams(c *gin.Context, kind string) (*entities.Revision, bool) {
+	ctx := c.Request.Context()
+
+	kindIDStr := c.Param("id")
+	kindID, err := strconv.ParseInt(kindIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'id' param=[%v]", kindIDStr),
+		})
+		return nil, false
+	}
+
+	revisionStr := c.Param("revision")
+	revisionNum, err := strconv.Atoi(revisionStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("failed to parse 'revision' param=[%v]", revisionStr),
+		})
+		return nil, false
+	}
+
+	revision, err := repos.NewRevisionDB(s.env.Database()).ByRevision(ctx, kind, kindID, revisionNum)
+	if err != nil {
+		logger.Errorf("failed to get revision %v of %v %v: %v", revisionNum, kind, kindID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get revision",
+		})
+		return nil, false
+	}
+
+	if revision == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("revision %v not found", revisionNum),
+		})
+		return nil, false
+	}
+
+	return revision, true
+}
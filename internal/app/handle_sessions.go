@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleApiListSessions lists the caller's active sessions, flagging the one
+// making the request.
+func (s *Server) HandleApiListSessions() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		tokenInfo := ctxhelper.TokenInfo(ctx)
+
+		sessions, err := repos.NewSessionDB(s.env.Database()).ActiveByUser(ctx, tokenInfo.UserID)
+		if err != nil {
+			logger.Errorf("failed to list sessions for user %v: %v", tokenInfo.UserID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list sessions",
+			})
+			return
+		}
+
+		for _, session := range sessions {
+			session.Current = session.ID == tokenInfo.SessionID
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    sessions,
+		})
+	}
+}
+
+func (s *Server) HandleApiRevokeSession() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		sessionIDStr := c.Param("id")
+		sessionID, err := strconv.ParseInt(sessionIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", sessionIDStr),
+			})
+			return
+		}
+
+		userID := ctxhelper.UserID(ctx)
+
+		revoked, err := repos.NewSessionDB(s.env.Database()).Deactivate(ctx, sessionID, userID)
+		if err != nil {
+			logger.Errorf("failed to revoke session %v of user %v: %v", sessionID, userID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error revoking the session",
+			})
+			return
+		}
+
+		if !revoked {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "session not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// HandleApiRevokeOtherSessions logs the caller out everywhere except the
+// session making the request.
+func (s *Server) HandleApiRevokeOtherSessions() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		tokenInfo := ctxhelper.TokenInfo(ctx)
+
+		revoked, err := repos.NewSessionDB(s.env.Database()).DeactivateOthers(ctx, tokenInfo.UserID, tokenInfo.SessionID)
+		if err != nil {
+			logger.Errorf("failed to revoke other sessions of user %v: %v", tokenInfo.UserID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error revoking sessions",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"revoked": revoked,
+			},
+		})
+	}
+}
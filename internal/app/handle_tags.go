@@ -0,0 +1,57 @@
+package app
+
+import (
+	"net/http"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) HandleListTags() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		filter, err := webutils.FilterFromContext(c)
+		if err != nil {
+			logger.Errorf("Failed to parse pagination filter for selecting tags: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "Failed to parse pagination",
+			})
+			return
+		}
+
+		db := repos.NewTagDB(s.env.Database())
+		tags, err := db.List(ctx, filter)
+		if err != nil {
+			logger.Errorf("failed to list tags: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not list tags",
+			})
+			return
+		}
+
+		count, err := db.Count(ctx, filter)
+		if err != nil {
+			logger.Errorf("failed to count tags: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not count tags",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"tags":       tags,
+				"pagination": entities.NewPagination(*count, filter.Page, filter.Per),
+			},
+		})
+	}
+}
@@ -0,0 +1,523 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/totp"
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	loginChallengeTokenSize = 32
+	recoveryCodeCount       = 10
+	recoveryCodeSize        = 5
+)
+
+// totpOptions accepts a code from the step either side of the current one
+// to allow for clock drift on the user's device.
+var totpOptions = totp.Options{Skew: 1}
+
+type (
+	twoFactorCodeFormData struct {
+		Code string `json:"code" form:"code" binding:"required"`
+	}
+
+	twoFactorPasswordFormData struct {
+		Password string `json:"password" form:"password" binding:"required"`
+	}
+
+	loginChallengeFormData struct {
+		ChallengeToken string `json:"challenge_token" form:"challenge_token" binding:"required"`
+		Code           string `json:"code" form:"code" binding:"required"`
+	}
+)
+
+// startLoginChallenge answers a correct password from a user with 2FA with
+// a short-lived challenge token instead of a session.
+func (s *Server) startLoginChallenge(c *gin.Context, user *entities.User) {
+	ctx := c.Request.Context()
+
+	token, err := util.GenerateSecureToken(loginChallengeTokenSize)
+	if err != nil {
+		logger.Errorf("failed to generate login challenge token: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "encountered an error logging in",
+		})
+		return
+	}
+
+	challenge := entities.NewLoginChallenge()
+	challenge.UserID = user.ID
+	challenge.TokenHash = util.HashToken(token)
+	challenge.ExpiresAt = time.Now().Add(s.config.LoginChallengeTTL)
+
+	if err := repos.NewTwoFactorDB(s.env.Database()).CreateChallenge(ctx, challenge); err != nil {
+		logger.Errorf("failed to save login challenge for user %v: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "encountered an error logging in",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"two_factor_required": true,
+		"challenge_token":     token,
+		"expires_at":          challenge.ExpiresAt,
+	})
+}
+
+// HandleLoginTwoFactor exchanges a login challenge and a TOTP or recovery
+// code for a session.
+func (s *Server) HandleLoginTwoFactor(sessionAuthenticator auth.SessionAuthenticator) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form loginChallengeFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		db := repos.NewTwoFactorDB(s.env.Database())
+		challenge, err := db.ChallengeByTokenHash(ctx, util.HashToken(strings.TrimSpace(form.ChallengeToken)))
+		if err != nil {
+			logger.Errorf("failed to get login challenge: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error logging in",
+			})
+			return
+		}
+
+		if challenge == nil || !challenge.IsUsable(time.Now()) {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"message": "invalid or expired login challenge, log in again",
+			})
+			return
+		}
+
+		user, err := repos.NewUserDB(s.env.Database()).GetByID(ctx, challenge.UserID)
+		if err != nil {
+			logger.Errorf("failed to get user by id %v: %v", challenge.UserID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error logging in",
+			})
+			return
+		}
+
+		if user == nil || !user.TwoFactorEnabled() || !user.Status.IsActive() {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"message": "invalid or expired login challenge, log in again",
+			})
+			return
+		}
+
+		// Wrong codes count as failed logins of the account, so fetching a new
+		// challenge every few guesses still runs into the login lockout.
+		email := strings.ToLower(user.Email)
+		emailFailures, ipFailures, allowed := s.checkLoginLockout(c, email)
+		if !allowed {
+			return
+		}
+
+		if err := db.ClaimChallengeAttempt(ctx, challenge); err != nil {
+			if errors.Is(err, repos.ErrLoginChallengeUsed) {
+				s.recordLoginFailure(c, email, user, emailFailures, ipFailures)
+				c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"success": false,
+					"message": "invalid or expired login challenge, log in again",
+				})
+				return
+			}
+			logger.Errorf("failed to claim login challenge %v: %v", challenge.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error logging in",
+			})
+			return
+		}
+
+		ok, err := s.checkSecondFactor(ctx, user, form.Code)
+		if err != nil {
+			logger.Errorf("failed to check second factor for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error logging in",
+			})
+			return
+		}
+
+		if !ok {
+			s.recordLoginFailure(c, email, user, emailFailures, ipFailures)
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"message": "invalid two-factor code",
+			})
+			return
+		}
+
+		if err := db.UseChallenge(ctx, challenge); err != nil {
+			if errors.Is(err, repos.ErrLoginChallengeUsed) {
+				c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"success": false,
+					"message": "invalid or expired login challenge, log in again",
+				})
+				return
+			}
+			logger.Errorf("failed to use login challenge %v: %v", challenge.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error logging in",
+			})
+			return
+		}
+
+		s.recordLoginAttempt(ctx, email, true)
+		s.startSession(c, sessionAuthenticator, user)
+	}
+}
+
+// checkSecondFactor accepts either a current TOTP code that has not been
+// used before or an unused recovery code.
+func (s *Server) checkSecondFactor(ctx context.Context, user *entities.User, code string) (bool, error) {
+	db := repos.NewTwoFactorDB(s.env.Database())
+
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(user.TOTPSecret.String, code, time.Now(), totpOptions); ok {
+		return db.UseStep(ctx, user.ID, step)
+	}
+
+	return db.UseRecoveryCode(ctx, user.ID, util.HashToken(normalizeRecoveryCode(code)))
+}
+
+func (s *Server) HandleApiTwoFactorStatus() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		user, ok := s.currentUser(c)
+		if !ok {
+			return
+		}
+
+		remaining := 0
+		if user.TwoFactorEnabled() {
+			count, err := repos.NewTwoFactorDB(s.env.Database()).CountRecoveryCodes(ctx, user.ID)
+			if err != nil {
+				logger.Errorf("failed to count recovery codes for user %v: %v", user.ID, err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not count recovery codes",
+				})
+				return
+			}
+			remaining = *count
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": map[string]interface{}{
+				"enabled":                  user.TwoFactorEnabled(),
+				"enabled_at":               user.TOTPEnabledAt,
+				"recovery_codes_remaining": remaining,
+			},
+		})
+	}
+}
+
+// HandleApiEnrollTwoFactor starts enrollment by generating a secret. 2FA
+// stays off until the first code is confirmed.
+func (s *Server) HandleApiEnrollTwoFactor() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		user, ok := s.currentUser(c)
+		if !ok {
+			return
+		}
+
+		if user.TwoFactorEnabled() {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "two-factor authentication is already enabled",
+			})
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			logger.Errorf("failed to generate totp secret: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error enrolling two-factor authentication",
+			})
+			return
+		}
+
+		if err := repos.NewTwoFactorDB(s.env.Database()).SetPendingSecret(ctx, user.ID, secret); err != nil {
+			if errors.Is(err, repos.ErrTwoFactorState) {
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": "two-factor authentication is already enabled",
+				})
+				return
+			}
+			logger.Errorf("failed to store totp secret for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error enrolling two-factor authentication",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "add the secret to your authenticator app and confirm with a code",
+			"data": map[string]interface{}{
+				"secret": secret,
+				"uri":    totp.URI(s.config.TwoFactorIssuer, user.Email, secret, totpOptions),
+			},
+		})
+	}
+}
+
+// HandleApiConfirmTwoFactor turns 2FA on once the user proves their app
+// produces valid codes, and returns the recovery codes once.
+func (s *Server) HandleApiConfirmTwoFactor() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form twoFactorCodeFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		user, ok := s.currentUser(c)
+		if !ok {
+			return
+		}
+
+		if user.TwoFactorEnabled() || !user.TOTPSecret.Valid {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "there is no two-factor enrollment to confirm",
+			})
+			return
+		}
+
+		step, valid := totp.Validate(user.TOTPSecret.String, form.Code, time.Now(), totpOptions)
+		if !valid {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid two-factor code",
+			})
+			return
+		}
+
+		codes, hashes, err := generateRecoveryCodes()
+		if err != nil {
+			logger.Errorf("failed to generate recovery codes: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error enabling two-factor authentication",
+			})
+			return
+		}
+
+		if err := repos.NewTwoFactorDB(s.env.Database()).Enable(ctx, user.ID, step, hashes); err != nil {
+			if errors.Is(err, repos.ErrTwoFactorState) {
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": "there is no two-factor enrollment to confirm",
+				})
+				return
+			}
+			logger.Errorf("failed to enable totp for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error enabling two-factor authentication",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "store the recovery codes now, they will not be shown again",
+			"data": map[string]interface{}{
+				"recovery_codes": codes,
+			},
+		})
+	}
+}
+
+func (s *Server) HandleApiRegenerateRecoveryCodes() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		user, ok := s.currentUserWithPassword(c)
+		if !ok {
+			return
+		}
+
+		if !user.TwoFactorEnabled() {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "two-factor authentication is not enabled",
+			})
+			return
+		}
+
+		codes, hashes, err := generateRecoveryCodes()
+		if err != nil {
+			logger.Errorf("failed to generate recovery codes: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error generating recovery codes",
+			})
+			return
+		}
+
+		if err := repos.NewTwoFactorDB(s.env.Database()).RegenerateRecoveryCodes(ctx, user.ID, hashes); err != nil {
+			logger.Errorf("failed to regenerate recovery codes for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error generating recovery codes",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "store the recovery codes now, they will not be shown again",
+			"data": map[string]interface{}{
+				"recovery_codes": codes,
+			},
+		})
+	}
+}
+
+// HandleApiDisableTwoFactor turns 2FA off. It needs the account password so
+// a stolen session alone cannot remove the second factor.
+func (s *Server) HandleApiDisableTwoFactor() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		user, ok := s.currentUserWithPassword(c)
+		if !ok {
+			return
+		}
+
+		if !user.TOTPSecret.Valid {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "two-factor authentication is not enabled",
+			})
+			return
+		}
+
+		if err := repos.NewTwoFactorDB(s.env.Database()).Disable(ctx, user.ID); err != nil {
+			logger.Errorf("failed to disable totp for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error disabling two-factor authentication",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "two-factor authentication disabled",
+		})
+	}
+}
+
+// currentUser loads the caller, writing an error response when it cannot.
+func (s *Server) currentUser(c *gin.Context) (*entities.User, bool) {
+	ctx := c.Request.Context()
+
+	userID := ctxhelper.UserID(ctx)
+	user, err := repos.NewUserDB(s.env.Database()).GetByID(ctx, userID)
+	if err != nil || user == nil {
+		logger.Errorf("failed to get user by id %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get user",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// currentUserWithPassword loads the caller after checking the password in
+// the request body.
+func (s *Server) currentUserWithPassword(c *gin.Context) (*entities.User, bool) {
+	var form twoFactorPasswordFormData
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": "invalid form provided",
+		})
+		return nil, false
+	}
+
+	user, ok := s.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	if !s.checkCurrentPassword(c, user, form.Password, "password is incorrect") {
+		return nil, false
+	}
+
+	return user, true
+}
+
+// generateRecoveryCodes returns codes like "1f9c2-a07be" along with the
+// hashes to store for them.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		raw := hex.EncodeToString(b)
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, util.HashToken(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"goquizbox/internal/entities"
 	"goquizbox/internal/logger"
 	"goquizbox/internal/repos"
+	"goquizbox/internal/web/auth"
 	"goquizbox/internal/web/ctxhelper"
 	"goquizbox/internal/web/webutils"
 
@@ -38,33 +40,67 @@ func (s *Server) HandleListUsers() func(c *gin.Context) {
 			return
 		}
 
-		db := repos.NewUserDB(s.env.Database())
-		users, err := db.List(ctx, filter)
+		order, err := webutils.OrderFilterFromContext(c)
 		if err != nil {
-			logger.Errorf("failed to list users: %v", err)
-			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
 				"success": false,
-				"message": "could not list users",
+				"message": err.Error(),
 			})
 			return
 		}
 
-		count, err := db.Count(ctx, filter)
+		if !s.decodeCursor(c, filter) {
+			return
+		}
+
+		// Only admins may search by email or see it, everyone else gets
+		// public profiles.
+		admin := auth.HasVerifiedRole(c, s.env, entities.UserRoleAdmin)
+
+		db := repos.NewUserDB(s.env.Database())
+		users, page, err := db.List(ctx, filter, order, admin)
+		if listRequestError(c, err, order) {
+			return
+		}
 		if err != nil {
-			logger.Errorf("failed to count users: %v", err)
+			logger.Errorf("failed to list users: %v", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
 				"success": false,
-				"message": "could not count users",
+				"message": "could not list users",
 			})
 			return
 		}
 
-		pagination := entities.NewPagination(*count, filter.Page, filter.Per)
+		var pagination interface{}
+		if filter.CursorMode {
+			pagination = s.cursorPagination(page, filter.Per)
+		} else {
+			count, err := db.Count(ctx, filter, admin)
+			if err != nil {
+				logger.Errorf("failed to count users: %v", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not count users",
+				})
+				return
+			}
+
+			pagination = entities.NewPagination(*count, filter.Page, filter.Per)
+		}
+
+		var data interface{} = users
+		if !admin {
+			public := make([]*entities.PublicUser, 0, len(users))
+			for _, user := range users {
+				public = append(public, user.Public())
+			}
+			data = public
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"data": map[string]interface{}{
-				"users":      users,
+				"users":      data,
 				"pagination": pagination,
 			},
 		})
@@ -96,6 +132,25 @@ func (s *Server) HandleGetUser() func(c *gin.Context) {
 			return
 		}
 
+		if user == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "user not found",
+			})
+			return
+		}
+
+		// Only the user themselves and admins see the full record. This is a
+		// public route, so the token has to be checked against its session
+		// first.
+		if auth.VerifiedUserID(c, s.env) != user.ID && !auth.HasVerifiedRole(c, s.env, entities.UserRoleAdmin) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"data":    user.Public(),
+			})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"data":    user,
@@ -156,10 +211,18 @@ func (s *Server) HandleApiUpdateUser() func(c *gin.Context) {
 			return
 		}
 
+		// A new address has to be verified again, otherwise a verified user
+		// could take over someone else's address and have their provider
+		// logins linked to this account.
+		emailChanged := !strings.EqualFold(user.Email, form.Email)
+
 		user.FirstName = form.FirstName
 		user.LastName = form.LastName
 		user.Email = form.Email
 		user.UpdatedAt = null.TimeFrom(time.Now())
+		if emailChanged {
+			user.EmailVerified = false
+		}
 
 		err = db.Save(ctx, user)
 		if err != nil {
@@ -171,6 +234,12 @@ func (s *Server) HandleApiUpdateUser() func(c *gin.Context) {
 			return
 		}
 
+		if emailChanged {
+			if err := s.sendActivationEmail(ctx, user); err != nil {
+				logger.Errorf("failed to send activation email to user %v: %v", user.ID, err)
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"data":    user,
@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/mailer"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/util"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+type (
+	verifyEmailFormData struct {
+		Email string `json:"email" form:"email" binding:"required"`
+		Code  string `json:"code" form:"code" binding:"required"`
+	}
+
+	resendVerificationFormData struct {
+		Email string `json:"email" form:"email" binding:"required"`
+	}
+)
+
+func (s *Server) HandleVerifyEmail() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form verifyEmailFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		db := repos.NewUserDB(s.env.Database())
+		user, err := db.ByEmail(ctx, strings.TrimSpace(form.Email))
+		if err != nil {
+			logger.Errorf("get user by email failed: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered error searching user by email",
+			})
+			return
+		}
+
+		if user != nil && user.EmailVerified {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"message": "email is already verified",
+			})
+			return
+		}
+
+		if user == nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid or expired verification code",
+			})
+			return
+		}
+
+		key, sentAt, err := db.ClaimEmailActivation(ctx, user.ID, s.config.EmailVerificationAttempts)
+		if err != nil && !errors.Is(err, repos.ErrActivationExhausted) {
+			logger.Errorf("failed to claim email activation attempt for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error verifying email",
+			})
+			return
+		}
+
+		if err != nil || !s.activationKeyMatches(key, sentAt, form.Code) {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid or expired verification code",
+			})
+			return
+		}
+
+		if err := db.VerifyEmail(ctx, user.ID); err != nil {
+			logger.Errorf("failed to verify email for user %v: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error verifying email",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "email verified, you can now log in",
+		})
+	}
+}
+
+// HandleResendVerification sends a fresh activation code. The response is
+// the same whether or not the email is registered.
+func (s *Server) HandleResendVerification() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var form resendVerificationFormData
+		if err := c.ShouldBindJSON(&form); err != nil {
+			logger.Errorf("failed to bind form: %v", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": "invalid form provided",
+			})
+			return
+		}
+
+		user, err := repos.NewUserDB(s.env.Database()).ByEmail(ctx, strings.TrimSpace(form.Email))
+		if err != nil {
+			logger.Errorf("get user by email failed: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered error searching user by email",
+			})
+			return
+		}
+
+		// Codes are not resent during the cooldown and send failures are only
+		// logged, so every email gets the same response and it does not give
+		// away which ones are registered.
+		coolingDown := user != nil && user.EmailActivationAt.Valid &&
+			time.Since(user.EmailActivationAt.Time) < s.config.EmailResendCooldown
+
+		if user != nil && !user.EmailVerified && !coolingDown {
+			if err := s.sendActivationEmail(ctx, user); err != nil {
+				logger.Errorf("failed to resend activation email to user %v: %v", user.ID, err)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "if that email is registered and unverified, a new activation code has been sent",
+		})
+	}
+}
+
+func (s *Server) activationKeyMatches(key string, sentAt time.Time, code string) bool {
+	if time.Since(sentAt) > s.config.EmailVerificationTTL {
+		return false
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return subtle.ConstantTimeCompare([]byte(key), []byte(code)) == 1
+}
+
+// sendActivationEmail issues a new activation key to the user, replacing any
+// earlier one, and mails it.
+func (s *Server) sendActivationEmail(ctx context.Context, user *entities.User) error {
+	key, err := util.GenerateEmailActivationKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate activation key: %w", err)
+	}
+	sentAt := time.Now()
+
+	if err := repos.NewUserDB(s.env.Database()).SetEmailActivationKey(ctx, user.ID, key, sentAt); err != nil {
+		return err
+	}
+
+	user.EmailActivationKey = null.StringFrom(key)
+	user.EmailActivationAt = null.TimeFrom(sentAt)
+
+	return s.env.Mailer().Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your goquizbox email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour goquizbox activation code is %s. It expires in %s.\n",
+			user.FirstName, key, s.config.EmailVerificationTTL,
+		),
+	})
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postOwnerID returns the author of the question or answer identified by
+// kind and id. A nil result means the post does not exist.
+func (s *Server) postOwnerID(ctx context.Context, kind string, id int64) (*int64, error) {
+	switch kind {
+	case entities.PostKindQuestion:
+		question, err := repos.NewQuestionDB(s.env.Database()).ByID(ctx, id)
+		if err != nil || question == nil {
+			return nil, err
+		}
+		return &question.UserID, nil
+	case entities.PostKindAnswer:
+		answer, err := repos.NewAnswerDB(s.env.Database()).ByID(ctx, id)
+		if err != nil || answer == nil {
+			return nil, err
+		}
+		return &answer.UserID, nil
+	}
+	return nil, fmt.Errorf("unknown post kind %q", kind)
+}
+
+// postOwnerFromParam looks up the author of the post in the 'id' path param
+// for privilege checks. An unparsable id is left for the handler to reject.
+func (s *Server) postOwnerFromParam(kind string) auth.OwnerLookup {
+	return func(c *gin.Context) (*int64, error) {
+		kindID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			return nil, nil
+		}
+		return s.postOwnerID(c.Request.Context(), kind, kindID)
+	}
+}
+
+func (s *Server) attachQuestionVotes(ctx context.Context, questions ...*entities.Question) error {
+	ids := make([]int64, 0, len(questions))
+	for _, question := range questions {
+		ids = append(ids, question.ID)
+	}
+
+	db := repos.NewVoteDB(s.env.Database())
+	summaries, err := db.Summaries(ctx, entities.VoteKindQuestion, ids, ctxhelper.UserID(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, question := range questions {
+		question.Votes = summaries[question.ID]
+	}
+	return nil
+}
+
+func (s *Server) attachAnswerVotes(ctx context.Context, answers ...*entities.Answer) error {
+	ids := make([]int64, 0, len(answers))
+	for _, answer := range answers {
+		ids = append(ids, answer.ID)
+	}
+
+	db := repos.NewVoteDB(s.env.Database())
+	summaries, err := db.Summaries(ctx, entities.VoteKindAnswer, ids, ctxhelper.UserID(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, answer := range answers {
+		answer.Votes = summaries[answer.ID]
+	}
+	return nil
+}
+
+func (s *Server) HandleApiVote(kind, mode string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		kindIDStr := c.Param("id")
+		kindID, err := strconv.ParseInt(kindIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", kindIDStr),
+			})
+			return
+		}
+
+		ownerID, err := s.postOwnerID(ctx, kind, kindID)
+		if err != nil {
+			logger.Errorf("failed to get %v by id %v: %v", kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("could not get %v", kind),
+			})
+			return
+		}
+
+		if ownerID == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("%v not found", kind),
+			})
+			return
+		}
+
+		userID := ctxhelper.UserID(ctx)
+		if *ownerID == userID {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("you cannot vote on your own %v", kind),
+			})
+			return
+		}
+
+		vote := entities.NewVote()
+		vote.UserID = userID
+		vote.KindID = kindID
+		vote.Kind = kind
+		vote.Mode = mode
+
+		if err := repos.NewVoteDB(s.env.Database()).Save(ctx, vote); err != nil {
+			logger.Errorf("failed to save vote: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error saving the vote",
+			})
+			return
+		}
+
+		s.respondWithVoteSummary(c, kind, kindID)
+	}
+}
+
+func (s *Server) HandleApiRetractVote(kind string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		kindIDStr := c.Param("id")
+		kindID, err := strconv.ParseInt(kindIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("failed to parse 'id' param=[%v]", kindIDStr),
+			})
+			return
+		}
+
+		userID := ctxhelper.UserID(ctx)
+
+		db := repos.NewVoteDB(s.env.Database())
+		vote, err := db.ByUserAndKind(ctx, userID, kindID, kind)
+		if err != nil {
+			logger.Errorf("failed to get vote by user %v on %v %v: %v", userID, kind, kindID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not get vote",
+			})
+			return
+		}
+
+		if vote == nil {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("you have not voted on this %v", kind),
+			})
+			return
+		}
+
+		if err := db.Delete(ctx, vote.ID); err != nil {
+			logger.Errorf("failed to delete vote %v: %v", vote.ID, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "encountered an error retracting the vote",
+			})
+			return
+		}
+
+		s.respondWithVoteSummary(c, kind, kindID)
+	}
+}
+
+func (s *Server) respondWithVoteSummary(c *gin.Context, kind string, kindID int64) {
+	ctx := c.Request.Context()
+
+	db := repos.NewVoteDB(s.env.Database())
+	summaries, err := db.Summaries(ctx, kind, []int64{kindID}, ctxhelper.UserID(ctx))
+	if err != nil {
+		logger.Errorf("failed to summarize votes for %v %v: %v", kind, kindID, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": "could not get votes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summaries[kindID],
+	})
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/web/webutils"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+// minCursorKeyLength is the shortest CURSOR_SIGNING_KEY accepted.
+const minCursorKeyLength = 32
+
+// decodeCursor reads the cursor query param into filter, writing an error
+// response when it is not one we issued.
+func (s *Server) decodeCursor(c *gin.Context, filter *webutils.Filter) bool {
+	if filter.RawCursor == "" {
+		return true
+	}
+
+	cursor, err := s.cursors.Decode(filter.RawCursor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": "invalid cursor provided",
+		})
+		return false
+	}
+
+	filter.Cursor = cursor
+	return true
+}
+
+// listRequestError writes a 400 response when err comes from a listing
+// asked for an unsupported order or given a cursor from another one.
+func listRequestError(c *gin.Context, err error, order *webutils.OrderFilter) bool {
+	switch {
+	case errors.Is(err, repos.ErrUnknownSort):
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("invalid order_by query given [%v]", order.Field),
+		})
+		return true
+	case errors.Is(err, webutils.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": "cursor does not match the requested order",
+		})
+		return true
+	}
+	return false
+}
+
+func (s *Server) cursorPagination(page *webutils.CursorPage, per int) *entities.CursorPagination {
+	pagination := &entities.CursorPagination{Per: per}
+	if page.Next != nil {
+		pagination.NextCursor = null.StringFrom(s.cursors.Encode(page.Next))
+	}
+	if page.Prev != nil {
+		pagination.PrevCursor = null.StringFrom(s.cursors.Encode(page.Prev))
+	}
+	return pagination
+}
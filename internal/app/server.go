@@ -5,16 +5,28 @@ import (
 	"fmt"
 	"net/http"
 
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
 	"goquizbox/internal/middleware"
+	"goquizbox/internal/oidc"
+	"goquizbox/internal/repos"
 	"goquizbox/internal/serverenv"
+	"goquizbox/internal/views"
 	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/webutils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	config *Config
-	env    *serverenv.ServerEnv
+	config     *Config
+	env        *serverenv.ServerEnv
+	jwtKeys    *auth.KeySet
+	jwtHandler auth.JWTHandler
+	oidc       *oidc.Registry
+	limits     middleware.LimitStore
+	cursors    *webutils.CursorCodec
+	views      *views.Counter
 }
 
 func NewServer(config *Config, env *serverenv.ServerEnv) (*Server, error) {
@@ -22,36 +34,122 @@ func NewServer(config *Config, env *serverenv.ServerEnv) (*Server, error) {
 		return nil, fmt.Errorf("missing Database in server env")
 	}
 
+	if env.Mailer() == nil {
+		return nil, fmt.Errorf("missing Mailer in server env")
+	}
+
+	jwtKeys, err := auth.LoadKeySet(&config.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwt keys: %w", err)
+	}
+
+	oidcRegistry, err := oidc.NewRegistry(config.OIDCProviders, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure oidc providers: %w", err)
+	}
+
+	if err := config.validateIntervals(); err != nil {
+		return nil, err
+	}
+
+	if config.ViewDedupMaxViewers <= 0 {
+		return nil, fmt.Errorf("VIEW_DEDUP_MAX_VIEWERS must be positive, got %v", config.ViewDedupMaxViewers)
+	}
+
+	if err := gin.New().SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	if len(config.CursorSigningKey) < minCursorKeyLength {
+		return nil, fmt.Errorf("CURSOR_SIGNING_KEY must be at least %d characters", minCursorKeyLength)
+	}
+
+	var limits middleware.LimitStore
+	switch config.RateLimitStore {
+	case "memory":
+		limits = middleware.NewMemoryLimitStore()
+	case "postgres":
+		limits = middleware.NewPostgresLimitStore(env.Database())
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", config.RateLimitStore)
+	}
+
 	return &Server{
-		config: config,
-		env:    env,
+		config:     config,
+		env:        env,
+		jwtKeys:    jwtKeys,
+		jwtHandler: auth.NewJWTHandler(jwtKeys),
+		oidc:       oidcRegistry,
+		limits:     limits,
+		cursors:    webutils.NewCursorCodec([]byte(config.CursorSigningKey)),
+		views:      views.NewCounter(repos.NewQuestionDB(env.Database()), config.ViewDedupWindow, config.ViewDedupMaxViewers),
 	}, nil
 }
 
+// Views returns the counter question views are recorded in.
+func (s *Server) Views() *views.Counter {
+	return s.views
+}
+
 func (s *Server) Routes(ctx context.Context) http.Handler {
 	mux := gin.New()
 
+	// Only believe forwarded client IPs set by our own proxies. The list
+	// was validated in NewServer.
+	if err := mux.SetTrustedProxies(s.config.TrustedProxies); err != nil {
+		logger.Errorf("failed to set trusted proxies: %v", err)
+	}
+
 	// Recovery middleware recovers from any panics and writes a 500 if there was one.
 	mux.Use(gin.Recovery())
 
-	sessionAuthenticator := auth.NewSessionAuthenticator(s.env)
+	sessionAuthenticator := auth.NewApiKeyAuthenticator(auth.NewSessionAuthenticator(s.jwtHandler, s.env), s.env)
 
 	defaultMiddlewares := middleware.DefaultMiddlewares(sessionAuthenticator)
 	mux.Use(defaultMiddlewares...)
 
 	// Healthz page
 	mux.GET("/healthz", s.HandleHealthz())
+	mux.GET("/.well-known/jwks.json", s.HandleJWKS())
+
+	authLimit := middleware.RateLimit("auth", s.config.RateLimitAuth, s.limits)
+	accountLimit := middleware.RateLimitAccount("auth", "email", s.config.RateLimitAuth, s.limits)
+	writeLimit := middleware.RateLimit("write", s.config.RateLimitWrite, s.limits)
 
 	apiRoutes := mux.Group("/api/v1")
 	{
-		apiRoutes.POST("users", s.HandleRegister())
-		apiRoutes.POST("auth/login", s.HandleLogin(sessionAuthenticator))
+		apiRoutes.POST("users", authLimit, accountLimit, s.HandleRegister())
+		apiRoutes.POST("auth/login", authLimit, accountLimit, s.HandleLogin(sessionAuthenticator))
+		apiRoutes.POST("auth/login/2fa", authLimit, s.HandleLoginTwoFactor(sessionAuthenticator))
+		apiRoutes.GET("auth/oidc/:provider/login", s.HandleOIDCLogin())
+		apiRoutes.GET("auth/oidc/:provider/callback", s.HandleOIDCCallback(sessionAuthenticator))
+		apiRoutes.POST("/users/verify", authLimit, accountLimit, s.HandleVerifyEmail())
+		apiRoutes.POST("/users/verify/resend", authLimit, accountLimit, s.HandleResendVerification())
+		apiRoutes.POST("/auth/password/forgot", authLimit, accountLimit, s.HandleForgotPassword())
+		apiRoutes.POST("/auth/password/reset", authLimit, s.HandleResetPassword())
 		apiRoutes.GET("/users", s.HandleListUsers())
 		apiRoutes.GET("/users/:id", s.HandleGetUser())
+		apiRoutes.GET("/users/:id/reputation", s.HandleGetUserReputation())
+		apiRoutes.GET("/users/:id/badges", s.HandleListUserBadges())
+		apiRoutes.GET("/badges", s.HandleListBadges())
+		apiRoutes.GET("/badges/:slug/users", s.HandleListBadgeHolders())
+
+		apiRoutes.GET("/tags", s.HandleListTags())
 
 		apiRoutes.GET("/questions", s.HandleListQuestions())
+		apiRoutes.GET("/feeds/hot", s.HandleListQuestionFeed(entities.FeedHot))
+		apiRoutes.GET("/feeds/trending", s.HandleListQuestionFeed(entities.FeedTrending))
+		apiRoutes.GET("/feeds/unanswered", s.HandleListQuestionFeed(entities.FeedUnanswered))
 		apiRoutes.GET("/questions/:id", s.HandleApiGetQuestion())
 		apiRoutes.GET("/questions/:id/answers", s.HandleApiGetQuestionAnswers())
+		apiRoutes.GET("/questions/:id/revisions", s.HandleApiListRevisions(entities.PostKindQuestion))
+		apiRoutes.GET("/questions/:id/revisions/:revision", s.HandleApiGetRevision(entities.PostKindQuestion))
+		apiRoutes.GET("/questions/:id/revisions/:revision/diff", s.HandleApiDiffRevisions(entities.PostKindQuestion))
+		apiRoutes.GET("/answers/:id/revisions", s.HandleApiListRevisions(entities.PostKindAnswer))
+		apiRoutes.GET("/answers/:id/revisions/:revision", s.HandleApiGetRevision(entities.PostKindAnswer))
+		apiRoutes.GET("/answers/:id/revisions/:revision/diff", s.HandleApiDiffRevisions(entities.PostKindAnswer))
+		apiRoutes.GET("/questions/:id/comments", s.HandleApiListComments(entities.PostKindQuestion))
+		apiRoutes.GET("/answers/:id/comments", s.HandleApiListComments(entities.PostKindAnswer))
 
 		securedApiRoutes := apiRoutes.Group("")
 		securedApiRoutes.Use(auth.AllowOnlyActiveUser(
@@ -59,12 +157,77 @@ func (s *Server) Routes(ctx context.Context) http.Handler {
 			s.env,
 		))
 		{
+			requirePrivilege := func(privilege entities.Privilege, owner auth.OwnerLookup) gin.HandlerFunc {
+				return auth.RequirePrivilege(privilege, &s.config.Privileges, s.env, owner)
+			}
+			editOthersQuestions := requirePrivilege(entities.PrivilegeEditOthers, s.postOwnerFromParam(entities.PostKindQuestion))
+			editOthersAnswers := requirePrivilege(entities.PrivilegeEditOthers, s.postOwnerFromParam(entities.PostKindAnswer))
+			closeVote := requirePrivilege(entities.PrivilegeVoteToClose, s.postOwnerFromParam(entities.PostKindQuestion))
+
 			securedApiRoutes.PUT("/users/:id", s.HandleApiUpdateUser())
 			securedApiRoutes.DELETE("/users/:id", s.HandleApiDeleteUser())
-			securedApiRoutes.DELETE("/auth/logout", s.HandleApiLogoutUser())
+			sessionOnly := auth.RequireSession()
+
+			securedApiRoutes.DELETE("/auth/logout", sessionOnly, s.HandleApiLogoutUser())
+			securedApiRoutes.PUT("/auth/password", sessionOnly, authLimit, s.HandleApiChangePassword())
+			securedApiRoutes.POST("/auth/oidc/:provider/link", sessionOnly, s.HandleApiLinkOIDC())
+			securedApiRoutes.GET("/auth/sessions", sessionOnly, s.HandleApiListSessions())
+			securedApiRoutes.DELETE("/auth/sessions", sessionOnly, s.HandleApiRevokeOtherSessions())
+			securedApiRoutes.DELETE("/auth/sessions/:id", sessionOnly, s.HandleApiRevokeSession())
+			securedApiRoutes.GET("/auth/2fa", sessionOnly, s.HandleApiTwoFactorStatus())
+			securedApiRoutes.POST("/auth/2fa/enroll", sessionOnly, s.HandleApiEnrollTwoFactor())
+			securedApiRoutes.POST("/auth/2fa/confirm", sessionOnly, s.HandleApiConfirmTwoFactor())
+			securedApiRoutes.POST("/auth/2fa/recovery-codes", sessionOnly, authLimit, s.HandleApiRegenerateRecoveryCodes())
+			securedApiRoutes.POST("/auth/2fa/disable", sessionOnly, authLimit, s.HandleApiDisableTwoFactor())
+
+			securedApiRoutes.POST("/api-keys", sessionOnly, s.HandleApiCreateApiKey())
+			securedApiRoutes.GET("/api-keys", sessionOnly, s.HandleApiListApiKeys())
+			securedApiRoutes.PUT("/api-keys/:id", sessionOnly, s.HandleApiRenameApiKey())
+			securedApiRoutes.DELETE("/api-keys/:id", sessionOnly, s.HandleApiRevokeApiKey())
+
+			securedApiRoutes.GET("/feeds/my-tags", s.HandleListQuestionFeed(entities.FeedMyTags))
+
+			securedApiRoutes.POST("/questions", writeLimit, s.HandleApiAddQuestion())
+			securedApiRoutes.PUT("/questions/:id", editOthersQuestions, s.HandleApiUpdateQuestion())
+			securedApiRoutes.DELETE("/questions/:id", s.HandleApiDeleteQuestion())
+			securedApiRoutes.POST("/questions/:id/answers", writeLimit, s.HandleApiAddQuestionAnswer())
+			securedApiRoutes.POST("/questions/:id/answers/:answerId/accept", s.HandleApiAcceptAnswer())
+			securedApiRoutes.DELETE("/questions/:id/answers/:answerId/accept", s.HandleApiUnacceptAnswer())
+			securedApiRoutes.POST("/questions/:id/close", closeVote, s.HandleApiCloseQuestionVote())
+			securedApiRoutes.POST("/questions/:id/reopen", closeVote, s.HandleApiReopenQuestionVote())
+			securedApiRoutes.PUT("/answers/:id", editOthersAnswers, s.HandleApiUpdateAnswer())
+			securedApiRoutes.DELETE("/answers/:id", s.HandleApiDeleteAnswer())
+
+			securedApiRoutes.POST("/questions/:id/revisions/:revision/rollback", editOthersQuestions, s.HandleApiRollbackRevision(entities.PostKindQuestion))
+			securedApiRoutes.POST("/answers/:id/revisions/:revision/rollback", editOthersAnswers, s.HandleApiRollbackRevision(entities.PostKindAnswer))
+
+			securedApiRoutes.POST("/questions/:id/upvote", s.HandleApiVote(entities.VoteKindQuestion, entities.VoteModeUp))
+			securedApiRoutes.POST("/questions/:id/downvote", requirePrivilege(entities.PrivilegeDownvote, nil), s.HandleApiVote(entities.VoteKindQuestion, entities.VoteModeDown))
+			securedApiRoutes.DELETE("/questions/:id/vote", s.HandleApiRetractVote(entities.VoteKindQuestion))
+			securedApiRoutes.POST("/answers/:id/upvote", s.HandleApiVote(entities.VoteKindAnswer, entities.VoteModeUp))
+			securedApiRoutes.POST("/answers/:id/downvote", requirePrivilege(entities.PrivilegeDownvote, nil), s.HandleApiVote(entities.VoteKindAnswer, entities.VoteModeDown))
+			securedApiRoutes.DELETE("/answers/:id/vote", s.HandleApiRetractVote(entities.VoteKindAnswer))
+
+			securedApiRoutes.POST("/questions/:id/comments", requirePrivilege(entities.PrivilegeComment, s.postOwnerFromParam(entities.PostKindQuestion)), writeLimit, s.HandleApiAddComment(entities.PostKindQuestion))
+			securedApiRoutes.POST("/answers/:id/comments", requirePrivilege(entities.PrivilegeComment, s.postOwnerFromParam(entities.PostKindAnswer)), writeLimit, s.HandleApiAddComment(entities.PostKindAnswer))
+			securedApiRoutes.PUT("/comments/:id", s.HandleApiUpdateComment())
+			securedApiRoutes.DELETE("/comments/:id", s.HandleApiDeleteComment())
+		}
 
-			securedApiRoutes.POST("/questions", s.HandleApiAddQuestion())
-			securedApiRoutes.POST("/questions/:id/answers", s.HandleApiAddQuestionAnswer())
+		adminApiRoutes := apiRoutes.Group("/admin")
+		adminApiRoutes.Use(
+			auth.AllowOnlyActiveUser(sessionAuthenticator, s.env),
+			auth.RequireRole(entities.UserRoleModerator),
+		)
+		{
+			adminOnly := auth.RequireRole(entities.UserRoleAdmin)
+
+			adminApiRoutes.GET("/users", adminOnly, s.HandleListUsers())
+			adminApiRoutes.PUT("/users/:id/status", s.HandleAdminSetUserStatus())
+			adminApiRoutes.PUT("/users/:id/role", adminOnly, s.HandleAdminSetUserRole())
+			adminApiRoutes.DELETE("/questions/:id", s.HandleAdminDeletePost(entities.PostKindQuestion))
+			adminApiRoutes.DELETE("/answers/:id", s.HandleAdminDeletePost(entities.PostKindAnswer))
+			adminApiRoutes.DELETE("/comments/:id", s.HandleAdminDeleteComment())
 		}
 	}
 
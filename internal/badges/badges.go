@@ -0,0 +1,84 @@
+// Package badges defines the badges users can earn and awards them.
+//
+// Each badge is a rule: a query selecting the user_id and context of every
+// user who qualifies. The engine runs the rules periodically and records new
+// awards, so adding a badge only takes a new entry in rules.
+package badges
+
+import (
+	"goquizbox/internal/entities"
+)
+
+// answerScoresSQL yields the net vote score of every answer with an author.
+const answerScoresSQL = `select a.id, a.user_id, a.question_id,
+	coalesce(sum(case when v.mode = 'up' then 1 when v.mode = 'down' then -1 end), 0) as score
+	from answers a left join votes v on v.kind = 'answer' and v.kind_id = a.id
+	where a.user_id is not null group by a.id`
+
+type Rule struct {
+	Badge *entities.Badge
+	Query string
+}
+
+var rules = []*Rule{
+	{
+		Badge: &entities.Badge{
+			Slug:        "first-question",
+			Name:        "First Question",
+			Description: "Asked a first question",
+			Class:       entities.BadgeClassBronze,
+		},
+		Query: `select distinct user_id, '' as context from questions where user_id is not null`,
+	},
+	{
+		Badge: &entities.Badge{
+			Slug:        "teacher",
+			Name:        "Teacher",
+			Description: "Answered a question with a score of 1 or more",
+			Class:       entities.BadgeClassBronze,
+		},
+		Query: `select distinct user_id, '' as context from (` + answerScoresSQL + `) s where score >= 1`,
+	},
+	{
+		Badge: &entities.Badge{
+			Slug:        "nice-answer",
+			Name:        "Nice Answer",
+			Description: "Answer score of 10 or more",
+			Class:       entities.BadgeClassBronze,
+		},
+		Query: `select user_id, id::text as context from (` + answerScoresSQL + `) s where score >= 10`,
+	},
+	{
+		Badge: &entities.Badge{
+			Slug:        "tag-expert",
+			Name:        "Tag Expert",
+			Description: "Total answer score of 100 or more in a tag",
+			Class:       entities.BadgeClassSilver,
+		},
+		Query: `select s.user_id, t.name as context from (` + answerScoresSQL + `) s
+			join question_tags qt on qt.question_id = s.question_id
+			join tags t on t.id = qt.tag_id
+			group by s.user_id, t.name having sum(s.score) >= 100`,
+	},
+}
+
+// All returns every badge that can be earned.
+func All() []*entities.Badge {
+	badges := make([]*entities.Badge, 0, len(rules))
+	for _, rule := range rules {
+		badge := *rule.Badge
+		badges = append(badges, &badge)
+	}
+	return badges
+}
+
+// Lookup returns the badge with the given slug, nil if there is none.
+func Lookup(slug string) *entities.Badge {
+	for _, rule := range rules {
+		if rule.Badge.Slug == slug {
+			badge := *rule.Badge
+			return &badge
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package badges
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"goquizbox/internal/entities"
+)
+
+func TestRules(t *testing.T) {
+	classes := map[string]bool{
+		entities.BadgeClassBronze: true,
+		entities.BadgeClassSilver: true,
+		entities.BadgeClassGold:   true,
+	}
+	slugPattern := regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		badge := rule.Badge
+		if !slugPattern.MatchString(badge.Slug) {
+			t.Errorf("badge slug %q is not kebab-case", badge.Slug)
+		}
+		if seen[badge.Slug] {
+			t.Errorf("badge slug %q is used by more than one rule", badge.Slug)
+		}
+		seen[badge.Slug] = true
+
+		if badge.Name == "" || badge.Description == "" {
+			t.Errorf("badge %q needs a name and description", badge.Slug)
+		}
+		if !classes[badge.Class] {
+			t.Errorf("badge %q has unknown class %q", badge.Slug, badge.Class)
+		}
+
+		// Award inserts the rows a rule selects as (user_id, context).
+		query := strings.Join(strings.Fields(rule.Query), " ")
+		if !strings.HasPrefix(query, "select ") || !strings.Contains(query, "user_id") {
+			t.Errorf("badge %q query does not select user_id: %v", badge.Slug, query)
+		}
+		if !strings.Contains(query, " as context from ") {
+			t.Errorf("badge %q query does not select a context: %v", badge.Slug, query)
+		}
+	}
+}
+
+func TestAnswerScoreThresholds(t *testing.T) {
+	cases := map[string]string{
+		"teacher":     "score >= 1",
+		"nice-answer": "score >= 10",
+		"tag-expert":  "sum(s.score) >= 100",
+	}
+
+	for slug, condition := range cases {
+		rule := ruleFor(t, slug)
+		if !strings.Contains(rule.Query, answerScoresSQL) {
+			t.Errorf("badge %q does not score answers by net votes", slug)
+		}
+		if !strings.Contains(rule.Query, condition) {
+			t.Errorf("badge %q does not require %q", slug, condition)
+		}
+	}
+
+	if !strings.Contains(answerScoresSQL, "a.user_id is not null") {
+		t.Error("answers without an author must not earn badges")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	badge := Lookup("first-question")
+	if badge == nil || badge.Name != "First Question" {
+		t.Fatalf("got %v", badge)
+	}
+
+	badge.Name = "changed"
+	if Lookup("first-question").Name != "First Question" {
+		t.Error("expected Lookup to return a copy")
+	}
+
+	if Lookup("missing") != nil {
+		t.Error("expected unknown slug to return nil")
+	}
+
+	if got := len(All()); got != len(rules) {
+		t.Errorf("got %d badges, want %d", got, len(rules))
+	}
+}
+
+func ruleFor(t *testing.T, slug string) *Rule {
+	t.Helper()
+
+	for _, rule := range rules {
+		if rule.Badge.Slug == slug {
+			return rule
+		}
+	}
+	t.Fatalf("no rule for badge %q", slug)
+	return nil
+}
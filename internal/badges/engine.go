@@ -0,0 +1,43 @@
+package badges
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/worker"
+)
+
+type Engine struct {
+	db *database.DB
+}
+
+func NewEngine(db *database.DB) *Engine {
+	return &Engine{
+		db: db,
+	}
+}
+
+// Sweep checks every rule and awards badges to users who newly qualify.
+func (e *Engine) Sweep(ctx context.Context) error {
+	db := repos.NewBadgeDB(e.db)
+	for _, rule := range rules {
+		awarded, err := db.Award(ctx, rule.Badge.Slug, rule.Query)
+		if err != nil {
+			return fmt.Errorf("sweep badges: %w", err)
+		}
+
+		if awarded > 0 {
+			logger.Infof("awarded %v badge %d times", rule.Badge.Slug, awarded)
+		}
+	}
+	return nil
+}
+
+// Run sweeps immediately and then every interval until ctx is done.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	worker.Every(ctx, "sweep badges", interval, e.Sweep)
+}
@@ -1,15 +1,32 @@
 package entities
 
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
 type Answer struct {
 	SequentialIdentifier
-	UserID     int64  `json:"user_id"`
-	QuestionID int64  `json:"question_id"`
-	Body       string `json:"body"`
+	UserID     int64        `json:"user_id"`
+	QuestionID int64        `json:"question_id"`
+	Body       string       `json:"body"`
+	Accepted   bool         `json:"accepted"`
+	Votes      *VoteSummary `json:"votes"`
+	// Highlight is only set when the answers are searched.
+	Highlight *AnswerHighlight `json:"highlight,omitempty"`
 	Timestamps
 }
 
+// AnswerHighlight holds snippets of a matching answer's body, HTML escaped
+// with the matched words wrapped in <mark> tags.
+type AnswerHighlight struct {
+	Body string `json:"body"`
+}
+
 func NewAnswer() *Answer {
-	return &Answer{}
+	return &Answer{
+		Votes: NewVoteSummary(),
+	}
 }
 
 func (c *Answer) Validate() []string {
@@ -17,5 +34,9 @@ func (c *Answer) Validate() []string {
 	if c.Body == "" {
 		errors = append(errors, "Body cannot be empty")
 	}
+
+	if utf8.RuneCountInString(c.Body) > maxPostBodyLength {
+		errors = append(errors, fmt.Sprintf("Body cannot be more than %d characters", maxPostBodyLength))
+	}
 	return errors
 }
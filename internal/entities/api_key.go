@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"strings"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	ApiKeyScopeRead  = "read"
+	ApiKeyScopeWrite = "write"
+)
+
+// ApiKey is a long lived credential for scripts. The key is presented as its
+// username and secret, only a hash of the secret is stored.
+type ApiKey struct {
+	SequentialIdentifier
+	UserID     int64     `json:"user_id"`
+	Name       string    `json:"name"`
+	Username   string    `json:"username"`
+	SecretHash string    `json:"-"`
+	Scope      string    `json:"scope"`
+	LastUsedAt null.Time `json:"last_used_at"`
+	RevokedAt  null.Time `json:"revoked_at"`
+	Timestamps
+}
+
+func NewApiKey() *ApiKey {
+	return &ApiKey{}
+}
+
+func (k *ApiKey) IsRevoked() bool {
+	return k.RevokedAt.Valid
+}
+
+func (k *ApiKey) Validate() []string {
+	errors := make([]string, 0)
+	if k.UserID < 1 {
+		errors = append(errors, "UserID cannot be empty")
+	}
+
+	if name := strings.TrimSpace(k.Name); name == "" || len(name) > 100 {
+		errors = append(errors, "Name must be between 1 and 100 characters")
+	}
+
+	if k.Scope != ApiKeyScopeRead && k.Scope != ApiKeyScopeWrite {
+		errors = append(errors, "Scope must be read or write")
+	}
+
+	if k.Username == "" || k.SecretHash == "" {
+		errors = append(errors, "Credentials cannot be empty")
+	}
+
+	return errors
+}
@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	AuditActionLoginLockout   = "login_lockout"
+	AuditActionIPLoginLockout = "ip_login_lockout"
+)
+
+// AuditLog records a security relevant event.
+type AuditLog struct {
+	SequentialIdentifier
+	UserID    null.Int               `json:"user_id"`
+	Action    string                 `json:"action"`
+	IPAddress string                 `json:"ip_address"`
+	UserAgent string                 `json:"user_agent"`
+	Details   map[string]interface{} `json:"details"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+func NewAuditLog() *AuditLog {
+	return &AuditLog{Details: map[string]interface{}{}}
+}
@@ -0,0 +1,34 @@
+package entities
+
+import "time"
+
+const (
+	BadgeClassBronze = "bronze"
+	BadgeClassSilver = "silver"
+	BadgeClassGold   = "gold"
+)
+
+type Badge struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Class       string `json:"class"`
+	Holders     int    `json:"holders"`
+}
+
+// UserBadge records a badge earned by a user. Badges that can be earned more
+// than once, such as one per answer or per tag, tell the awards apart by
+// Context.
+type UserBadge struct {
+	SequentialIdentifier
+	UserID    int64     `json:"user_id"`
+	Badge     string    `json:"badge"`
+	Name      string    `json:"name"`
+	Class     string    `json:"class"`
+	Context   string    `json:"context"`
+	AwardedAt time.Time `json:"awarded_at"`
+}
+
+func NewUserBadge() *UserBadge {
+	return &UserBadge{}
+}
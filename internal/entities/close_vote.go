@@ -0,0 +1,78 @@
+package entities
+
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	CloseReasonDuplicate    = "duplicate"
+	CloseReasonOffTopic     = "off_topic"
+	CloseReasonNeedsDetails = "needs_details"
+	CloseReasonOpinionBased = "opinion_based"
+
+	CloseVoteKindClose  = "close"
+	CloseVoteKindReopen = "reopen"
+)
+
+var closeReasons = map[string]bool{
+	CloseReasonDuplicate:    true,
+	CloseReasonOffTopic:     true,
+	CloseReasonNeedsDetails: true,
+	CloseReasonOpinionBased: true,
+}
+
+// CloseVote is a user's vote to close or reopen a question. Votes are
+// cleared once they close or reopen it, so a later round starts afresh.
+type CloseVote struct {
+	SequentialIdentifier
+	QuestionID    int64       `json:"question_id"`
+	UserID        int64       `json:"user_id"`
+	Kind          string      `json:"kind"`
+	Reason        null.String `json:"reason"`
+	DuplicateOfID null.Int    `json:"duplicate_of_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+func NewCloseVote() *CloseVote {
+	return &CloseVote{}
+}
+
+func (v *CloseVote) Validate() []string {
+	errors := make([]string, 0)
+	if v.QuestionID < 1 {
+		errors = append(errors, "QuestionID cannot be empty")
+	}
+
+	if v.UserID < 1 {
+		errors = append(errors, "UserID cannot be empty")
+	}
+
+	switch v.Kind {
+	case CloseVoteKindClose:
+		if !closeReasons[v.Reason.String] {
+			errors = append(errors, "Reason must be one of duplicate, off_topic, needs_details or opinion_based")
+		}
+
+		if v.Reason.String == CloseReasonDuplicate && !v.DuplicateOfID.Valid {
+			errors = append(errors, "A duplicate must name the question it duplicates")
+		}
+
+		if v.Reason.String != CloseReasonDuplicate && v.DuplicateOfID.Valid {
+			errors = append(errors, "Only duplicates can name another question")
+		}
+
+		if v.DuplicateOfID.Valid && v.DuplicateOfID.Int64 == v.QuestionID {
+			errors = append(errors, "A question cannot duplicate itself")
+		}
+	case CloseVoteKindReopen:
+		if v.Reason.Valid || v.DuplicateOfID.Valid {
+			errors = append(errors, "Reopen votes take no reason")
+		}
+	default:
+		errors = append(errors, "Kind must be close or reopen")
+	}
+
+	return errors
+}
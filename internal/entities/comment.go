@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	minCommentLength = 15
+	maxCommentLength = 600
+)
+
+// Comment is a short remark on a question or answer. Comments may have one
+// level of replies, a reply's ParentID points at a top level comment.
+type Comment struct {
+	SequentialIdentifier
+	Kind     string     `json:"kind"`
+	KindID   int64      `json:"kind_id"`
+	UserID   int64      `json:"user_id"`
+	ParentID null.Int   `json:"parent_id"`
+	Body     string     `json:"body"`
+	Replies  []*Comment `json:"replies,omitempty"`
+	Timestamps
+}
+
+func NewComment() *Comment {
+	return &Comment{}
+}
+
+func (c *Comment) IsReply() bool {
+	return c.ParentID.Valid
+}
+
+func (c *Comment) Validate() []string {
+	errors := make([]string, 0)
+	if c.Kind != PostKindQuestion && c.Kind != PostKindAnswer {
+		errors = append(errors, "Kind must be question or answer")
+	}
+
+	if c.KindID < 1 {
+		errors = append(errors, "KindID cannot be empty")
+	}
+
+	if c.UserID < 1 {
+		errors = append(errors, "UserID cannot be empty")
+	}
+
+	length := utf8.RuneCountInString(strings.TrimSpace(c.Body))
+	if length < minCommentLength || length > maxCommentLength {
+		errors = append(errors, fmt.Sprintf("Body must be between %d and %d characters", minCommentLength, maxCommentLength))
+	}
+	return errors
+}
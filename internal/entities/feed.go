@@ -0,0 +1,34 @@
+package entities
+
+import "time"
+
+// Question feeds. Hot and trending read scores kept up to date by a
+// background job, so listing them is a plain indexed scan.
+const (
+	// FeedHot ranks open questions by activity, decaying with age.
+	FeedHot = "hot"
+	// FeedTrending ranks questions by activity within TrendingWindow.
+	FeedTrending = "trending"
+	// FeedUnanswered lists open questions without an accepted or upvoted
+	// answer, newest first.
+	FeedUnanswered = "unanswered"
+	// FeedMyTags lists other people's questions in the tags a user has
+	// asked or answered in, newest first.
+	FeedMyTags = "my-tags"
+)
+
+const (
+	// HotGravity is how fast hot scores fall with age. A question's hot
+	// score is (points + 1) / (age in hours + 2) ^ HotGravity.
+	HotGravity = 1.5
+	// HotAnswerPoints and HotViewsPerPoint weigh answers and views against
+	// votes, each worth a point.
+	HotAnswerPoints  = 2
+	HotViewsPerPoint = 10
+	// HotScorePrecision is how many decimal places hot scores are kept to.
+	// A question with no activity decays to 0 in under two years and is
+	// then no longer rewritten.
+	HotScorePrecision = 6
+
+	TrendingWindow = 7 * 24 * time.Hour
+)
@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"time"
+)
+
+type (
+	LoginAttempt struct {
+		SequentialIdentifier
+		Email     string    `json:"email"`
+		IPAddress string    `json:"ip_address"`
+		Success   bool      `json:"success"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// LoginFailures summarises the failed attempts since the last success.
+	LoginFailures struct {
+		Count       int
+		LastFailure time.Time
+	}
+
+	// LoginBackoff locks logins out once Threshold failures pile up. Each
+	// failure past it doubles the lockout, starting at Base and capped at Max.
+	LoginBackoff struct {
+		Threshold int
+		Base      time.Duration
+		Max       time.Duration
+	}
+)
+
+func NewLoginAttempt() *LoginAttempt {
+	return &LoginAttempt{}
+}
+
+// LockedUntil returns when logins may be tried again after failures. A
+// zero time means there is no lockout.
+func (b LoginBackoff) LockedUntil(failures LoginFailures) time.Time {
+	if b.Threshold < 1 || failures.Count < b.Threshold {
+		return time.Time{}
+	}
+
+	lockout := b.Base
+	for i := b.Threshold; i < failures.Count && lockout < b.Max; i++ {
+		lockout *= 2
+	}
+	if lockout > b.Max {
+		lockout = b.Max
+	}
+
+	return failures.LastFailure.Add(lockout)
+}
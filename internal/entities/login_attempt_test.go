@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoffLockedUntil(t *testing.T) {
+	t.Parallel()
+
+	backoff := LoginBackoff{Threshold: 3, Base: time.Minute, Max: 10 * time.Minute}
+	last := time.Date(2023, 1, 30, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+
+	for _, tc := range cases {
+		got := backoff.LockedUntil(LoginFailures{Count: tc.failures, LastFailure: last})
+
+		if tc.want == 0 {
+			if !got.IsZero() {
+				t.Errorf("%d failures: expected no lockout, got %v", tc.failures, got)
+			}
+			continue
+		}
+
+		if want := last.Add(tc.want); !got.Equal(want) {
+			t.Errorf("%d failures: got %v, want %v", tc.failures, got, want)
+		}
+	}
+}
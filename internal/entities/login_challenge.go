@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+// MaxLoginChallengeAttempts is how many codes can be tried against a
+// challenge before it stops working and the user has to log in again.
+const MaxLoginChallengeAttempts = 5
+
+// LoginChallenge is the short-lived second step of a login for users with
+// two-factor authentication. Only a hash of the token is stored.
+type LoginChallenge struct {
+	SequentialIdentifier
+	UserID    int64     `json:"user_id"`
+	TokenHash string    `json:"-"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    null.Time `json:"used_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewLoginChallenge() *LoginChallenge {
+	return &LoginChallenge{}
+}
+
+func (c *LoginChallenge) IsUsable(now time.Time) bool {
+	return !c.UsedAt.Valid && c.Attempts < MaxLoginChallengeAttempts && now.Before(c.ExpiresAt)
+}
@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+type (
+	// OIDCLogin holds what is needed to finish a login started at an OpenID
+	// Connect provider. Only a hash of the state is stored. UserID is set
+	// when a signed in user started it to link the provider account.
+	OIDCLogin struct {
+		SequentialIdentifier
+		UserID       null.Int  `json:"user_id"`
+		Provider     string    `json:"provider"`
+		StateHash    string    `json:"-"`
+		Nonce        string    `json:"-"`
+		CodeVerifier string    `json:"-"`
+		ExpiresAt    time.Time `json:"expires_at"`
+		UsedAt       null.Time `json:"used_at"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+
+	// UserIdentity links a user to their account at an OpenID Connect
+	// provider.
+	UserIdentity struct {
+		SequentialIdentifier
+		UserID    int64     `json:"user_id"`
+		Provider  string    `json:"provider"`
+		Subject   string    `json:"subject"`
+		Email     string    `json:"email"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+)
+
+func NewOIDCLogin() *OIDCLogin {
+	return &OIDCLogin{}
+}
+
+func NewUserIdentity() *UserIdentity {
+	return &UserIdentity{}
+}
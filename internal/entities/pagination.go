@@ -44,3 +44,11 @@ func NewPagination(count, page, per int) *Pagination {
 		PrevPage: prevPage,
 	}
 }
+
+// CursorPagination links to the pages around one read by cursor. There is
+// no count, listings skip counting in cursor mode.
+type CursorPagination struct {
+	Per        int         `json:"per"`
+	NextCursor null.String `json:"next_cursor"`
+	PrevCursor null.String `json:"prev_cursor"`
+}
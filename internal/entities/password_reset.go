@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+// PasswordReset is a single-use token for setting a new password. Only a
+// hash of the token is stored.
+type PasswordReset struct {
+	SequentialIdentifier
+	UserID    int64     `json:"user_id"`
+	TokenHash string    `json:"-"`
+	IPAddress string    `json:"ip_address"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    null.Time `json:"used_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewPasswordReset() *PasswordReset {
+	return &PasswordReset{}
+}
+
+func (r *PasswordReset) IsUsable(now time.Time) bool {
+	return !r.UsedAt.Valid && now.Before(r.ExpiresAt)
+}
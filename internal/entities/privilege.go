@@ -0,0 +1,11 @@
+package entities
+
+// Privilege names an action that needs a minimum reputation.
+type Privilege string
+
+const (
+	PrivilegeDownvote    Privilege = "downvote"
+	PrivilegeComment     Privilege = "comment"
+	PrivilegeEditOthers  Privilege = "edit_others"
+	PrivilegeVoteToClose Privilege = "vote_to_close"
+)
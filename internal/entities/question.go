@@ -1,16 +1,54 @@
 package entities
 
+import (
+	"fmt"
+	"unicode/utf8"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+// maxPostBodyLength caps question and answer bodies, which also bounds the
+// work of diffing two of their revisions.
+const maxPostBodyLength = 30000
+
 type Question struct {
 	SequentialIdentifier
-	UserID int64  `json:"user_id"`
-	Title  string `json:"title"`
-	Body   string `json:"body"`
-	Tags   string `json:"tags"`
+	UserID           int64        `json:"user_id"`
+	Title            string       `json:"title"`
+	Body             string       `json:"body"`
+	Tags             []string     `json:"tags"`
+	AcceptedAnswerID null.Int     `json:"accepted_answer_id"`
+	Resolved         bool         `json:"resolved"`
+	Closed           bool         `json:"closed"`
+	ClosedAt         null.Time    `json:"closed_at"`
+	CloseReason      null.String  `json:"close_reason"`
+	DuplicateOfID    null.Int     `json:"duplicate_of_id"`
+	DuplicateOfURL   null.String  `json:"duplicate_of_url"`
+	Votes            *VoteSummary `json:"votes"`
+	ViewCount        int64        `json:"view_count"`
+	// Highlight is only set on search results.
+	Highlight *QuestionHighlight `json:"highlight,omitempty"`
 	Timestamps
 }
 
+// QuestionHighlight holds the title and body of a search result, HTML
+// escaped with the matched words wrapped in <mark> tags. The body is cut
+// down to snippets.
+type QuestionHighlight struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
 func NewQuestion() *Question {
-	return &Question{}
+	return &Question{
+		Tags:  []string{},
+		Votes: NewVoteSummary(),
+	}
+}
+
+// QuestionURL returns the API path of a question.
+func QuestionURL(id int64) string {
+	return fmt.Sprintf("/api/v1/questions/%d", id)
 }
 
 func (c *Question) Validate() []string {
@@ -23,5 +61,17 @@ func (c *Question) Validate() []string {
 		errors = append(errors, "Body cannot be empty")
 	}
 
+	if utf8.RuneCountInString(c.Body) > maxPostBodyLength {
+		errors = append(errors, fmt.Sprintf("Body cannot be more than %d characters", maxPostBodyLength))
+	}
+
+	if len(c.Tags) == 0 {
+		errors = append(errors, "Tags cannot be empty")
+	}
+
+	if len(c.Tags) > maxTagsPerQuestion {
+		errors = append(errors, fmt.Sprintf("Tags cannot be more than %d", maxTagsPerQuestion))
+	}
+
 	return errors
 }
@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	MinReputation = 1
+
+	ReputationQuestionUpvoted    = "question_upvoted"
+	ReputationAnswerUpvoted      = "answer_upvoted"
+	ReputationPostDownvoted      = "post_downvoted"
+	ReputationAnswerDownvoteCast = "answer_downvote_cast"
+	ReputationAnswerAccepted     = "answer_accepted"
+	ReputationAnswerAcceptCast   = "answer_accept_cast"
+)
+
+// ReputationEvent is a single entry in the reputation ledger. Points are
+// never edited or removed, taking points back records a revocation with the
+// negated amount.
+type ReputationEvent struct {
+	SequentialIdentifier
+	UserID     int64     `json:"user_id"`
+	Amount     int       `json:"amount"`
+	Reason     string    `json:"reason"`
+	Kind       string    `json:"kind"`
+	KindID     int64     `json:"kind_id"`
+	ActorID    null.Int  `json:"actor_id"`
+	Revocation bool      `json:"revocation"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewReputationEvent() *ReputationEvent {
+	return &ReputationEvent{}
+}
+
+// ReputationAward describes points handed out for an action, either to the
+// author of the post acted on or to the user taking the action.
+type ReputationAward struct {
+	Reason  string
+	Amount  int
+	ToActor bool
+}
+
+var (
+	voteAwards = map[string]map[string][]ReputationAward{
+		VoteKindQuestion: {
+			VoteModeUp: {
+				{Reason: ReputationQuestionUpvoted, Amount: 10},
+			},
+			VoteModeDown: {
+				{Reason: ReputationPostDownvoted, Amount: -2},
+			},
+		},
+		VoteKindAnswer: {
+			VoteModeUp: {
+				{Reason: ReputationAnswerUpvoted, Amount: 10},
+			},
+			VoteModeDown: {
+				{Reason: ReputationPostDownvoted, Amount: -2},
+				{Reason: ReputationAnswerDownvoteCast, Amount: -1, ToActor: true},
+			},
+		},
+	}
+
+	acceptAwards = []ReputationAward{
+		{Reason: ReputationAnswerAccepted, Amount: 15},
+		{Reason: ReputationAnswerAcceptCast, Amount: 2, ToActor: true},
+	}
+)
+
+// VoteAwards returns the points given out for a vote of mode on a post of
+// kind.
+func VoteAwards(kind, mode string) []ReputationAward {
+	return voteAwards[kind][mode]
+}
+
+// AcceptAwards returns the points given out when an answer is accepted.
+func AcceptAwards() []ReputationAward {
+	return acceptAwards
+}
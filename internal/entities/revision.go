@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"strings"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	PostKindQuestion = "question"
+	PostKindAnswer   = "answer"
+)
+
+// Revision is a snapshot of a question or answer as it stood after an edit.
+// Revision 1 is the content as originally posted.
+type Revision struct {
+	SequentialIdentifier
+	Kind     string      `json:"kind"`
+	KindID   int64       `json:"kind_id"`
+	Revision int         `json:"revision"`
+	UserID   int64       `json:"user_id"`
+	Title    null.String `json:"title"`
+	Body     string      `json:"body"`
+	Tags     null.String `json:"tags"`
+	Summary  null.String `json:"summary"`
+	Timestamps
+}
+
+func NewRevision() *Revision {
+	return &Revision{}
+}
+
+func NewQuestionRevision(question *Question, editorID int64, summary string) *Revision {
+	return &Revision{
+		Kind:    PostKindQuestion,
+		KindID:  question.ID,
+		UserID:  editorID,
+		Title:   null.StringFrom(question.Title),
+		Body:    question.Body,
+		Tags:    null.StringFrom(strings.Join(question.Tags, ",")),
+		Summary: null.NewString(summary, summary != ""),
+	}
+}
+
+func NewAnswerRevision(answer *Answer, editorID int64, summary string) *Revision {
+	return &Revision{
+		Kind:    PostKindAnswer,
+		KindID:  answer.ID,
+		UserID:  editorID,
+		Body:    answer.Body,
+		Summary: null.NewString(summary, summary != ""),
+	}
+}
+
+func (c *Revision) Validate() []string {
+	errors := make([]string, 0)
+	if c.Kind != PostKindQuestion && c.Kind != PostKindAnswer {
+		errors = append(errors, "Kind must be question or answer")
+	}
+
+	if c.KindID < 1 {
+		errors = append(errors, "KindID cannot be empty")
+	}
+
+	if c.UserID < 1 {
+		errors = append(errors, "UserID cannot be empty")
+	}
+
+	if len(c.Summary.String) > 255 {
+		errors = append(errors, "Summary cannot be longer than 255 characters")
+	}
+	return errors
+}
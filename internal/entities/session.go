@@ -15,6 +15,7 @@ type (
 		LastRefreshedAt time.Time `json:"last_refreshed_at"`
 		UserAgent       string    `json:"user_agent"`
 		UserID          int64     `json:"user_id"`
+		Current         bool      `json:"current"`
 		Timestamps
 	}
 
@@ -22,15 +23,27 @@ type (
 		SequentialIdentifier
 		ID              int64      `json:"id"`
 		DeactivatedAt   null.Time  `json:"deactivated_at"`
+		ExpiresAt       null.Time  `json:"expires_at"`
 		IPAddress       string     `json:"ip_address"`
 		LastRefreshedAt time.Time  `json:"last_refreshed_at"`
 		UserAgent       string     `json:"user_agent"`
 		UserID          int64      `json:"user_id"`
 		UserStatus      UserStatus `json:"user_status"`
+		UserRole        UserRole   `json:"user_role"`
 		Timestamps
 	}
 )
 
+// IsActive reports whether the session can still be used at now.
+func (c *Session) IsActive(now time.Time) bool {
+	return !c.DeactivatedAt.Valid && (!c.ExpiresAt.Valid || now.Before(c.ExpiresAt.Time))
+}
+
+// IsActive reports whether the session can still be used at now.
+func (c *FullSession) IsActive(now time.Time) bool {
+	return !c.DeactivatedAt.Valid && (!c.ExpiresAt.Valid || now.Before(c.ExpiresAt.Time))
+}
+
 func NewSession() *Session {
 	return &Session{}
 }
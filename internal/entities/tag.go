@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	maxTagLength       = 35
+	maxTagsPerQuestion = 5
+)
+
+var (
+	tagSpaceRegex   = regexp.MustCompile(`\s+`)
+	tagInvalidRegex = regexp.MustCompile(`[^a-z0-9+#.\-]`)
+)
+
+type Tag struct {
+	SequentialIdentifier
+	Name          string `json:"name"`
+	QuestionCount int    `json:"question_count"`
+	Timestamps
+}
+
+func NewTag() *Tag {
+	return &Tag{}
+}
+
+// NormalizeTag lower cases name, joins words with hyphens and drops any
+// character that is not allowed in a tag.
+func NormalizeTag(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = tagSpaceRegex.ReplaceAllString(name, "-")
+	name = tagInvalidRegex.ReplaceAllString(name, "")
+	if len(name) > maxTagLength {
+		name = name[:maxTagLength]
+	}
+	return name
+}
+
+// ParseTags splits a comma separated list of tags, normalizing each and
+// dropping empty and repeated ones.
+func ParseTags(raw string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		tag := NormalizeTag(part)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"lower", "Go", "go"},
+		{"trim", "  sql ", "sql"},
+		{"spaces", "Ruby on  Rails", "ruby-on-rails"},
+		{"symbols", "c#", "c#"},
+		{"invalid", "no/slash!", "noslash"},
+		{"long", "abcdefghijklmnopqrstuvwxyz0123456789xyz", "abcdefghijklmnopqrstuvwxyz012345678"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := NormalizeTag(tc.in); got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	t.Parallel()
+
+	got := ParseTags("Go, postgres,,go , full text search")
+	want := []string{"go", "postgres", "full-text-search"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -5,13 +5,33 @@ import (
 )
 
 type TokenInfo struct {
+	ApiKeyID  int64
 	Exp       time.Time
 	Refresh   time.Time
+	Role      string
+	Scope     string
 	SessionID int64
 	Status    string
 	UserID    int64
 }
 
+// IsApiKey reports whether the request authenticated with an API key rather
+// than a session token.
+func (ti *TokenInfo) IsApiKey() bool {
+	return ti.ApiKeyID > 0
+}
+
+// CanWrite reports whether the credentials allow changing data. Sessions
+// always can, API keys only with the write scope.
+func (ti *TokenInfo) CanWrite() bool {
+	return !ti.IsApiKey() || ti.Scope == ApiKeyScopeWrite
+}
+
+// HasRole reports whether the caller has at least role.
+func (ti *TokenInfo) HasRole(role UserRole) bool {
+	return UserRole(ti.Role).AtLeast(role)
+}
+
 func (ti *TokenInfo) RequiresRefresh() bool {
 	return time.Now().After(ti.Refresh)
 }
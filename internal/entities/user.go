@@ -3,6 +3,7 @@ package entities
 import (
 	"regexp"
 	"strings"
+	"time"
 
 	null "gopkg.in/guregu/null.v4"
 )
@@ -19,18 +20,51 @@ type User struct {
 	LastName           string      `json:"last_name"`
 	Email              string      `json:"email"`
 	EmailActivationKey null.String `json:"-"`
+	EmailActivationAt  null.Time   `json:"-"`
 	EmailVerified      bool        `json:"email_verified"`
 	Password           string      `json:"-"`
 	PasswordConfirm    string      `json:"-"`
 	PasswordHash       string      `json:"-"`
 	Status             UserStatus  `json:"status"`
+	Role               UserRole    `json:"role"`
+	Reputation         int         `json:"reputation"`
+	TOTPSecret         null.String `json:"-"`
+	TOTPEnabledAt      null.Time   `json:"-"`
+	TOTPLastStep       null.Int    `json:"-"`
 	Timestamps
 }
 
+// PublicUser is what anyone may see about a user.
+type PublicUser struct {
+	ID         int64     `json:"id"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Role       UserRole  `json:"role"`
+	Reputation int       `json:"reputation"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 func NewUser() *User {
 	return &User{}
 }
 
+func (c *User) Public() *PublicUser {
+	return &PublicUser{
+		ID:         c.ID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		Role:       c.Role,
+		Reputation: c.Reputation,
+		CreatedAt:  c.CreatedAt,
+	}
+}
+
+// TwoFactorEnabled reports whether logins need a TOTP code. A secret
+// without TOTPEnabledAt is an enrollment that was never confirmed.
+func (c *User) TwoFactorEnabled() bool {
+	return c.TOTPSecret.Valid && c.TOTPEnabledAt.Valid
+}
+
 func (c *User) Validate() []string {
 	errors := make([]string, 0)
 	if c.FirstName == "" || c.LastName == "" {
@@ -0,0 +1,45 @@
+package entities
+
+import (
+	"database/sql/driver"
+)
+
+type UserRole string
+
+const (
+	UserRoleUser      UserRole = "user"
+	UserRoleModerator UserRole = "moderator"
+	UserRoleAdmin     UserRole = "admin"
+)
+
+var userRoleRanks = map[UserRole]int{
+	UserRoleUser:      1,
+	UserRoleModerator: 2,
+	UserRoleAdmin:     3,
+}
+
+// Scan implements the Scanner interface.
+func (r *UserRole) Scan(value interface{}) error {
+	*r = UserRole(string(value.(string)))
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (r UserRole) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r UserRole) String() string {
+	return string(r)
+}
+
+func (r UserRole) IsValid() bool {
+	_, ok := userRoleRanks[r]
+	return ok
+}
+
+// AtLeast reports whether r has every power of role. Admins can do anything
+// moderators can, and moderators anything users can.
+func (r UserRole) AtLeast(role UserRole) bool {
+	return userRoleRanks[r] >= userRoleRanks[role] && r.IsValid()
+}
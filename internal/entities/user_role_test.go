@@ -0,0 +1,26 @@
+package entities
+
+import "testing"
+
+func TestUserRoleAtLeast(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		role UserRole
+		min  UserRole
+		want bool
+	}{
+		{UserRoleAdmin, UserRoleModerator, true},
+		{UserRoleModerator, UserRoleModerator, true},
+		{UserRoleUser, UserRoleModerator, false},
+		{UserRoleModerator, UserRoleAdmin, false},
+		{UserRole(""), UserRoleUser, false},
+		{UserRole("root"), UserRoleUser, false},
+	}
+
+	for _, tc := range cases {
+		if got := tc.role.AtLeast(tc.min); got != tc.want {
+			t.Errorf("%q.AtLeast(%q) = %v, want %v", tc.role, tc.min, got, tc.want)
+		}
+	}
+}
@@ -1,5 +1,17 @@
 package entities
 
+import (
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	VoteKindQuestion = "question"
+	VoteKindAnswer   = "answer"
+
+	VoteModeUp   = "up"
+	VoteModeDown = "down"
+)
+
 type Vote struct {
 	SequentialIdentifier
 	UserID int64  `json:"user_id"`
@@ -9,14 +21,37 @@ type Vote struct {
 	Timestamps
 }
 
+type VoteSummary struct {
+	Up       int         `json:"up"`
+	Down     int         `json:"down"`
+	Net      int         `json:"net"`
+	UserVote null.String `json:"user_vote"`
+}
+
 func NewVote() *Vote {
 	return &Vote{}
 }
 
+func NewVoteSummary() *VoteSummary {
+	return &VoteSummary{}
+}
+
 func (c *Vote) Validate() []string {
 	errors := make([]string, 0)
 	if c.UserID < 1 {
 		errors = append(errors, "UserID cannot be empty")
 	}
+
+	if c.KindID < 1 {
+		errors = append(errors, "KindID cannot be empty")
+	}
+
+	if c.Kind != VoteKindQuestion && c.Kind != VoteKindAnswer {
+		errors = append(errors, "Kind must be question or answer")
+	}
+
+	if c.Mode != VoteModeUp && c.Mode != VoteModeDown {
+		errors = append(errors, "Mode must be up or down")
+	}
 	return errors
 }
@@ -0,0 +1,49 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/worker"
+)
+
+// scoreRefresher recomputes question feed scores as of now.
+type scoreRefresher interface {
+	RefreshScores(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Scorer keeps the hot and trending scores the question feeds are ordered
+// by up to date.
+type Scorer struct {
+	questions scoreRefresher
+	now       func() time.Time
+}
+
+func NewScorer(db *database.DB) *Scorer {
+	return &Scorer{
+		questions: repos.NewQuestionDB(db),
+		now:       time.Now,
+	}
+}
+
+// Score recomputes every question's feed scores.
+func (s *Scorer) Score(ctx context.Context) error {
+	updated, err := s.questions.RefreshScores(ctx, s.now())
+	if err != nil {
+		return fmt.Errorf("score questions: %w", err)
+	}
+
+	if updated > 0 {
+		logger.Infof("updated feed scores of %d questions", updated)
+	}
+	return nil
+}
+
+// Run scores immediately and then every interval until ctx is done.
+func (s *Scorer) Run(ctx context.Context, interval time.Duration) {
+	worker.Every(ctx, "score questions", interval, s.Score)
+}
@@ -0,0 +1,36 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeRefresher struct {
+	calls []time.Time
+	err   error
+}
+
+func (r *fakeRefresher) RefreshScores(_ context.Context, now time.Time) (int64, error) {
+	r.calls = append(r.calls, now)
+	return 0, r.err
+}
+
+func TestScorerScore(t *testing.T) {
+	now := time.Date(2023, 2, 3, 9, 0, 0, 0, time.UTC)
+	refresher := &fakeRefresher{}
+	scorer := &Scorer{questions: refresher, now: func() time.Time { return now }}
+
+	if err := scorer.Score(context.Background()); err != nil {
+		t.Fatalf("Score: %v", err)
+	}
+	if len(refresher.calls) != 1 || !refresher.calls[0].Equal(now) {
+		t.Errorf("expected scores refreshed as of %v, got %v", now, refresher.calls)
+	}
+
+	refresher.err = errors.New("database down")
+	if err := scorer.Score(context.Background()); !errors.Is(err, refresher.err) {
+		t.Errorf("expected the refresh error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each message to its own file in a directory.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail dir: %w", err)
+	}
+
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	name := filepath.Join(m.dir, fmt.Sprintf("%d.eml", time.Now().UnixNano()))
+	if err := os.WriteFile(name, compose(m.from, msg), 0o644); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
+
+func compose(from string, msg *Message) []byte {
+	return []byte(fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		from, msg.To, msg.Subject, msg.Body,
+	))
+}
@@ -0,0 +1,23 @@
+package mailer
+
+import (
+	"context"
+
+	"goquizbox/internal/logger"
+)
+
+// LogMailer writes messages to the application log instead of sending them.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{
+		from: from,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	logger.Infof("mail from=[%v] to=[%v] subject=[%v]\n%v", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
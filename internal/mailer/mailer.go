@@ -0,0 +1,56 @@
+// Package mailer sends transactional email through a configurable backend.
+//
+// The log and file backends do not deliver anything and are meant for local
+// runs, the smtp backend delivers through a mail server.
+package mailer
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	BackendLog  = "log"
+	BackendFile = "file"
+	BackendSMTP = "smtp"
+)
+
+type Config struct {
+	Backend      string `env:"MAILER_BACKEND, default=log" json:",omitempty"`
+	From         string `env:"MAILER_FROM, default=noreply@goquizbox.local" json:",omitempty"`
+	Dir          string `env:"MAILER_DIR, default=/tmp/goquizbox-mail" json:",omitempty"`
+	SMTPHost     string `env:"MAILER_SMTP_HOST" json:",omitempty"`
+	SMTPPort     string `env:"MAILER_SMTP_PORT, default=587" json:",omitempty"`
+	SMTPUsername string `env:"MAILER_SMTP_USERNAME" json:",omitempty"`
+	SMTPPassword string `env:"MAILER_SMTP_PASSWORD" json:"-"` // ignored by zap's JSON formatter
+}
+
+func (c *Config) MailerConfig() *Config {
+	return c
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New returns the mailer for the configured backend.
+func New(config *Config) (Mailer, error) {
+	switch config.Backend {
+	case BackendLog:
+		return NewLogMailer(config.From), nil
+	case BackendFile:
+		return NewFileMailer(config.From, config.Dir)
+	case BackendSMTP:
+		if config.SMTPHost == "" {
+			return nil, fmt.Errorf("smtp mailer needs MAILER_SMTP_HOST")
+		}
+		return NewSMTPMailer(config), nil
+	}
+	return nil, fmt.Errorf("unknown mailer backend %q", config.Backend)
+}
@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{name: "log", config: &Config{Backend: BackendLog}},
+		{name: "file", config: &Config{Backend: BackendFile, Dir: t.TempDir()}},
+		{name: "smtp_without_host", config: &Config{Backend: BackendSMTP}, wantErr: true},
+		{name: "smtp", config: &Config{Backend: BackendSMTP, SMTPHost: "localhost", SMTPPort: "25"}},
+		{name: "unknown", config: &Config{Backend: "pigeon"}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(tc.config)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	m, err := NewFileMailer("noreply@example.com", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Send(context.Background(), &Message{
+		To:      "jane@example.com",
+		Subject: "Hello",
+		Body:    "Your code is abc12",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 mail file, got %d", len(files))
+	}
+
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"To: jane@example.com", "Subject: Hello", "Your code is abc12"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected mail to contain %q, got %q", want, b)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+type SMTPMailer struct {
+	from string
+	addr string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(config *Config) *SMTPMailer {
+	var auth smtp.Auth
+	if config.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
+	}
+
+	return &SMTPMailer{
+		from: config.From,
+		addr: net.JoinHostPort(config.SMTPHost, config.SMTPPort),
+		auth: auth,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, compose(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,6 @@
 package middleware
 
 import (
-	"net"
-	"net/http"
-	"strings"
-
 	"goquizbox/internal/logger"
 	"goquizbox/internal/util"
 	"goquizbox/internal/web/auth"
@@ -69,9 +65,12 @@ func setupAppContext(
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 
-		ipAddress, err := getIP(c.Request)
-		if err != nil {
-			logger.Warnf("Unable to parse ipAddress %v from remote address: %v", c.Request.RemoteAddr, err)
+		// ClientIP only follows X-Forwarded-For through the engine's trusted
+		// proxies, so the address cannot be picked by the client. Login
+		// lockouts and rate limits depend on that.
+		ipAddress := c.ClientIP()
+		if ipAddress == "" {
+			logger.Warnf("Unable to parse ipAddress from remote address %v", c.Request.RemoteAddr)
 		} else {
 			ctx = ctxhelper.WithIpAddress(ctx, ipAddress)
 		}
@@ -92,15 +91,3 @@ func setupAppContext(
 		c.Next()
 	}
 }
-
-func getIP(r *http.Request) (string, error) {
-	ips := r.Header.Get("X-FORWARDED-FOR")
-	forwarded := strings.Split(ips, ",")
-
-	if ips != "" && len(forwarded) > 0 {
-		return forwarded[0], nil
-	}
-
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	return host, err
-}
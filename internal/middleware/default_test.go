@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/web/auth"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+type anonymousAuthenticator struct {
+	auth.SessionAuthenticator
+}
+
+func (anonymousAuthenticator) TokenInfoFromRequest(*http.Request) (*entities.TokenInfo, error) {
+	return nil, auth.ErrTokenNotProvided
+}
+
+func TestSetupAppContextIPAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name    string
+		trusted []string
+		want    string
+	}{
+		{name: "untrusted proxy", want: "192.0.2.1"},
+		{name: "trusted proxy", trusted: []string{"192.0.2.0/24"}, want: "203.0.113.9"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := gin.New()
+			if err := mux.SetTrustedProxies(tc.trusted); err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			mux.GET("/", setupAppContext(anonymousAuthenticator{}), func(c *gin.Context) {
+				got = ctxhelper.IPAddress(c.Request.Context())
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Forwarded-For", "203.0.113.9")
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tc.want {
+				t.Errorf("got ip %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"goquizbox/internal/logger"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLimitBodyBytes bounds how much of a body is read to find the account a
+// request is for. Auth forms are far smaller.
+const maxLimitBodyBytes = 64 << 10
+
+type (
+	// Limit allows Requests per Per on average, with bursts of up to Burst.
+	// It is read from the environment as "requests/period", optionally
+	// followed by ",burst", e.g. "10/1m" or "30/1m,10".
+	Limit struct {
+		Requests int
+		Per      time.Duration
+		Burst    int
+	}
+
+	// LimitResult is the outcome of taking a token from a bucket.
+	LimitResult struct {
+		Allowed    bool
+		Remaining  int
+		RetryAfter time.Duration
+		// Reset is how long until the bucket is full again.
+		Reset time.Duration
+	}
+
+	// LimitStore keeps token buckets by key.
+	LimitStore interface {
+		Take(ctx context.Context, key string, limit Limit, now time.Time) (*LimitResult, error)
+	}
+)
+
+// EnvDecode lets go-envconfig read a Limit.
+func (l *Limit) EnvDecode(val string) error {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(val), ",")
+
+	requests, per, ok := strings.Cut(rate, "/")
+	if !ok {
+		return fmt.Errorf("invalid rate limit %q, expected requests/period", val)
+	}
+
+	var err error
+	if l.Requests, err = strconv.Atoi(strings.TrimSpace(requests)); err != nil || l.Requests < 1 {
+		return fmt.Errorf("invalid rate limit requests %q", requests)
+	}
+
+	if l.Per, err = time.ParseDuration(strings.TrimSpace(per)); err != nil || l.Per <= 0 {
+		return fmt.Errorf("invalid rate limit period %q", per)
+	}
+
+	l.Burst = l.Requests
+	if hasBurst {
+		if l.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || l.Burst < 1 {
+			return fmt.Errorf("invalid rate limit burst %q", burst)
+		}
+	}
+
+	return nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%v,%d", l.Requests, l.Per, l.Burst)
+}
+
+// interval is how long the bucket takes to gain one token.
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// take refills a bucket that held tokens at last and tries to take one from
+// it at now, returning the tokens left and the result.
+func (l Limit) take(tokens float64, last, now time.Time) (float64, *LimitResult) {
+	interval := l.interval()
+
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(float64(l.Burst), tokens+float64(elapsed)/float64(interval))
+	}
+
+	result := &LimitResult{}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(interval))
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((float64(l.Burst) - tokens) * float64(interval))
+
+	return tokens, result
+}
+
+// RateLimit limits requests to the routes it is used on, per user when the
+// request carries a token and per IP otherwise. Requests are let through
+// if the store fails, so an outage of it does not take the API down.
+func RateLimit(name string, limit Limit, store LimitStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := name + ":ip:" + c.ClientIP()
+		if userID := ctxhelper.UserID(c.Request.Context()); userID > 0 {
+			key = fmt.Sprintf("%v:user:%d", name, userID)
+		}
+
+		if takeLimit(c, name, key, limit, store) {
+			c.Next()
+		}
+	}
+}
+
+// RateLimitAccount limits requests per value of field in the request body,
+// such as the email being logged in to, so spreading attempts on one account
+// across many addresses still runs into its limit. Requests without the
+// field are left to the other limits.
+func RateLimitAccount(name, field string, limit Limit, store LimitStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account := strings.ToLower(strings.TrimSpace(bodyField(c, field)))
+		if account == "" {
+			c.Next()
+			return
+		}
+
+		if takeLimit(c, name, name+":account:"+account, limit, store) {
+			c.Next()
+		}
+	}
+}
+
+// takeLimit takes a token for key and reports whether the request may go
+// on, writing the 429 response when it may not. Store failures let the
+// request through.
+func takeLimit(c *gin.Context, name, key string, limit Limit, store LimitStore) bool {
+	result, err := store.Take(c.Request.Context(), key, limit, time.Now())
+	if err != nil {
+		logger.Errorf("failed to apply %v rate limit: %v", name, err)
+		return true
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, map[string]interface{}{
+			"success": false,
+			"message": "too many requests, slow down",
+		})
+		return false
+	}
+	return true
+}
+
+// bodyField reads field from a JSON or form body and puts the body back for
+// the handler to bind. JSON is tried whatever the Content-Type, since
+// handlers binding with ShouldBindJSON ignore it too.
+func bodyField(c *gin.Context, field string) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	original := c.Request.Body
+	body, err := io.ReadAll(io.LimitReader(original, maxLimitBodyBytes))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), original), original}
+	if err != nil {
+		return ""
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err == nil {
+		value, _ := fields[field].(string)
+		return value
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get(field)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/logger"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	insertRateLimitBucketSQL = `insert into rate_limit_buckets (key, tokens, updated_at) values ($1, $2, $3) on conflict (key) do nothing`
+	lockRateLimitBucketSQL   = `select tokens, updated_at from rate_limit_buckets where key = $1 for update`
+	updateRateLimitBucketSQL = `update rate_limit_buckets set tokens = $2, updated_at = $3 where key = $1`
+	deleteRateLimitBucketSQL = `delete from rate_limit_buckets where updated_at < $1`
+
+	// pruneEvery is how many takes happen between sweeps of idle buckets.
+	pruneEvery = 1000
+	// idleBucketAge is how long a bucket goes unused before it is dropped.
+	// Any limit with a period shorter than this is full again by then.
+	idleBucketAge = 24 * time.Hour
+)
+
+type (
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	// MemoryLimitStore keeps buckets in process. Each instance of the app
+	// counts on its own, use PostgresLimitStore when running several.
+	MemoryLimitStore struct {
+		mu      sync.Mutex
+		buckets map[string]*bucket
+		takes   int
+	}
+
+	// PostgresLimitStore shares buckets between instances through the
+	// rate_limit_buckets table.
+	PostgresLimitStore struct {
+		db *database.DB
+
+		mu    sync.Mutex
+		takes int
+	}
+)
+
+func NewMemoryLimitStore() *MemoryLimitStore {
+	return &MemoryLimitStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *MemoryLimitStore) Take(_ context.Context, key string, limit Limit, now time.Time) (*LimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%pruneEvery == 0 {
+		for k, b := range s.buckets {
+			if now.Sub(b.last) > idleBucketAge {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	var result *LimitResult
+	b.tokens, result = limit.take(b.tokens, b.last, now)
+	if now.After(b.last) {
+		b.last = now
+	}
+
+	return result, nil
+}
+
+func NewPostgresLimitStore(db *database.DB) *PostgresLimitStore {
+	return &PostgresLimitStore{
+		db: db,
+	}
+}
+
+func (s *PostgresLimitStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (*LimitResult, error) {
+	var result *LimitResult
+
+	if err := s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, insertRateLimitBucketSQL, key, float64(limit.Burst), now); err != nil {
+			return fmt.Errorf("failed to create bucket: %w", err)
+		}
+
+		var (
+			tokens float64
+			last   time.Time
+		)
+		if err := tx.QueryRow(ctx, lockRateLimitBucketSQL, key).Scan(&tokens, &last); err != nil {
+			return fmt.Errorf("failed to lock bucket: %w", err)
+		}
+
+		tokens, result = limit.take(tokens, last, now)
+		if last.After(now) {
+			now = last
+		}
+
+		if _, err := tx.Exec(ctx, updateRateLimitBucketSQL, key, tokens, now); err != nil {
+			return fmt.Errorf("failed to update bucket: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("take rate limit token: %w", err)
+	}
+
+	if s.shouldPrune() {
+		if err := s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, deleteRateLimitBucketSQL, now.Add(-idleBucketAge))
+			return err
+		}); err != nil {
+			logger.Errorf("failed to prune rate limit buckets: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *PostgresLimitStore) shouldPrune() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	return s.takes%pruneEvery == 0
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimitEnvDecode(t *testing.T) {
+	var limit Limit
+	if err := limit.EnvDecode("30/1m"); err != nil {
+		t.Fatalf("EnvDecode: %v", err)
+	}
+	if limit != (Limit{Requests: 30, Per: time.Minute, Burst: 30}) {
+		t.Errorf("got %v", limit)
+	}
+
+	if err := limit.EnvDecode("5/10s,2"); err != nil {
+		t.Fatalf("EnvDecode: %v", err)
+	}
+	if limit != (Limit{Requests: 5, Per: 10 * time.Second, Burst: 2}) {
+		t.Errorf("got %v", limit)
+	}
+
+	for _, val := range []string{"", "10", "0/1m", "10/x", "10/1m,0", "10/-1m"} {
+		if err := new(Limit).EnvDecode(val); err == nil {
+			t.Errorf("expected %q to be rejected", val)
+		}
+	}
+}
+
+func TestMemoryLimitStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryLimitStore()
+	limit := Limit{Requests: 1, Per: 10 * time.Second, Burst: 2}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		result, _ := store.Take(ctx, "a", limit, now)
+		if !result.Allowed || result.Remaining != 1-i {
+			t.Fatalf("take %d: got %+v", i, result)
+		}
+	}
+
+	result, _ := store.Take(ctx, "a", limit, now)
+	if result.Allowed || result.RetryAfter != 10*time.Second {
+		t.Fatalf("expected to be limited for 10s, got %+v", result)
+	}
+
+	if result, _ := store.Take(ctx, "b", limit, now); !result.Allowed {
+		t.Errorf("expected keys to have their own buckets")
+	}
+
+	if result, _ := store.Take(ctx, "a", limit, now.Add(5*time.Second)); result.Allowed {
+		t.Errorf("expected half a token not to be enough")
+	}
+
+	result, _ = store.Take(ctx, "a", limit, now.Add(10*time.Second))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("expected a token to refill, got %+v", result)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var (
+		userID    int64
+		forwarded string
+	)
+	mux := gin.New()
+	if err := mux.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	mux.Use(func(c *gin.Context) {
+		if userID > 0 {
+			ctx := ctxhelper.WithTokenInfo(c.Request.Context(), &entities.TokenInfo{UserID: userID})
+			c.Request = c.Request.WithContext(ctx)
+		}
+	})
+	mux.POST("/", RateLimit("test", Limit{Requests: 1, Per: time.Minute, Burst: 1}, NewMemoryLimitStore()), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	do := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if forwarded != "" {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do()
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected first request through, got %v", w.Code)
+	}
+	if got := w.Header().Get("X-RateLimit-Limit"); got != "1" {
+		t.Errorf("X-RateLimit-Limit = %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q", got)
+	}
+
+	w = do()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %v", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q", got)
+	}
+
+	forwarded = "203.0.113.9"
+	if w := do(); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected a spoofed X-Forwarded-For to share the IP's limit, got %v", w.Code)
+	}
+
+	userID = 7
+	if w := do(); w.Code != http.StatusNoContent {
+		t.Errorf("expected the user to be limited apart from the IP, got %v", w.Code)
+	}
+}
+
+func TestRateLimitAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.POST("/", RateLimitAccount("test", "email", Limit{Requests: 1, Per: time.Minute, Burst: 1}, NewMemoryLimitStore()), func(c *gin.Context) {
+		var form struct {
+			Email string `json:"email" form:"email"`
+		}
+		if err := c.ShouldBind(&form); err != nil || form.Email == "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	do := func(contentType, body string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := do("application/json", `{"email": "Ann@example.com"}`); code != http.StatusNoContent {
+		t.Fatalf("expected first request through with its body intact, got %v", code)
+	}
+	if code := do("application/x-www-form-urlencoded", "email=ann%40example.com+"); code != http.StatusTooManyRequests {
+		t.Errorf("expected the same account to be limited, got %v", code)
+	}
+	if code := do("application/json", `{"email": "bob@example.com"}`); code != http.StatusNoContent {
+		t.Errorf("expected another account through, got %v", code)
+	}
+	if code := do("text/plain", `{"email": "bob@example.com"}`); code != http.StatusTooManyRequests {
+		t.Errorf("expected a JSON body sent as text/plain to count against its account, got %v", code)
+	}
+	if code := do("application/json", `{}`); code != http.StatusBadRequest {
+		t.Errorf("expected requests without the field to reach the handler, got %v", code)
+	}
+}
@@ -0,0 +1,96 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+type (
+	jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+
+	jwkSet struct {
+		Keys []jwk `json:"keys"`
+	}
+)
+
+// publicKeys returns the signing keys in the set by kid. Keys of types we
+// cannot verify with are skipped.
+func (s *jwkSet) publicKeys() (map[string]interface{}, error) {
+	keys := make(map[string]interface{}, len(s.Keys))
+
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, err := decodeBigInt(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			e, err := decodeBigInt(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			curve, ok := map[string]elliptic.Curve{
+				"P-256": elliptic.P256(),
+				"P-384": elliptic.P384(),
+				"P-521": elliptic.P521(),
+			}[k.Crv]
+			if !ok {
+				continue
+			}
+			x, err := decodeBigInt(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			y, err := decodeBigInt(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+
+	return keys, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// keyMatchesMethod stops a token from picking an algorithm the key was not
+// meant for.
+func keyMatchesMethod(key interface{}, method jwt.SigningMethod) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, ok := method.(*jwt.SigningMethodRSA)
+		return ok
+	case *ecdsa.PublicKey:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok
+	}
+	return false
+}
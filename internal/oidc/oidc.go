@@ -0,0 +1,380 @@
+// Package oidc implements the parts of OpenID Connect needed to log users in
+// through an external provider: discovery, the authorization code flow with
+// PKCE, and ID token validation.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"goquizbox/internal/util"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	discoveryPath    = "/.well-known/openid-configuration"
+	codeVerifierSize = 32
+	maxResponseSize  = 1 << 20
+)
+
+var ErrUnknownProvider = errors.New("unknown oidc provider")
+
+type (
+	// ProviderConfig describes one provider. Scopes defaults to openid,
+	// email and profile.
+	ProviderConfig struct {
+		Name         string   `json:"name"`
+		Issuer       string   `json:"issuer"`
+		ClientID     string   `json:"client_id"`
+		ClientSecret string   `json:"client_secret"`
+		RedirectURL  string   `json:"redirect_url"`
+		Scopes       []string `json:"scopes"`
+	}
+
+	// Providers is read from a JSON array in the environment.
+	Providers []ProviderConfig
+
+	Discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+
+	Tokens struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		IDToken     string `json:"id_token"`
+	}
+
+	// Claims are the ID token claims we use.
+	Claims struct {
+		Subject       string
+		Email         string
+		EmailVerified bool
+		Name          string
+		GivenName     string
+		FamilyName    string
+	}
+
+	Provider struct {
+		config ProviderConfig
+		client *http.Client
+
+		mu        sync.Mutex
+		discovery *Discovery
+		keys      map[string]interface{}
+	}
+
+	Registry struct {
+		providers map[string]*Provider
+	}
+)
+
+// EnvDecode lets go-envconfig read Providers from a JSON array.
+func (p *Providers) EnvDecode(val string) error {
+	if strings.TrimSpace(val) == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(val), p)
+}
+
+func NewRegistry(providers Providers, client *http.Client) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider, len(providers))}
+
+	for _, config := range providers {
+		if config.Name == "" || config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc provider %q needs a name, issuer, client_id and redirect_url", config.Name)
+		}
+		if _, ok := registry.providers[config.Name]; ok {
+			return nil, fmt.Errorf("oidc provider %q is configured twice", config.Name)
+		}
+		registry.providers[config.Name] = NewProvider(config, client)
+	}
+
+	return registry, nil
+}
+
+func (r *Registry) Provider(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+func NewProvider(config ProviderConfig, client *http.Client) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Provider{
+		config: config,
+		client: client,
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// GenerateCodeVerifier returns a random PKCE code verifier.
+func GenerateCodeVerifier() (string, error) {
+	return util.GenerateSecureToken(codeVerifierSize)
+}
+
+// CodeChallenge derives the S256 PKCE challenge for verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns where to send the user to log in.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange trades an authorization code for tokens.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*Tokens, error) {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var tokens Tokens
+	if err := p.do(req, &tokens); err != nil {
+		return nil, fmt.Errorf("token exchange: %w", err)
+	}
+
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token exchange: no id_token in response")
+	}
+
+	return &tokens, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token and returns its claims.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*Claims, error) {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
+		return p.verificationKey(ctx, token)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid id token claims")
+	}
+
+	if iss, _ := claims["iss"].(string); iss != discovery.Issuer {
+		return nil, fmt.Errorf("id token issued by %q, expected %q", iss, discovery.Issuer)
+	}
+
+	if !hasAudience(claims["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("id token is not meant for this client")
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("id token has no expiry")
+	}
+
+	if got, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("id token nonce mismatch")
+	}
+
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	result.GivenName, _ = claims["given_name"].(string)
+	result.FamilyName, _ = claims["family_name"].(string)
+
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	if result.Subject == "" {
+		return nil, fmt.Errorf("id token has no subject")
+	}
+
+	return result, nil
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if s, _ := a.(string); s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Discovery fetches and caches the provider's metadata.
+func (p *Provider) Discovery(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.config.Issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery Discovery
+	if err := p.do(req, &discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+
+	if discovery.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", discovery.Issuer, p.config.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery: incomplete provider metadata")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// verificationKey finds the key for token's kid, refetching the provider's
+// keys once when the kid is unknown since that is how providers rotate.
+func (p *Provider) verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		keys, err := p.loadKeys(ctx, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := keys[kid]
+		if !ok {
+			continue
+		}
+
+		if !keyMatchesMethod(key, token.Method) {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Method.Alg())
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *Provider) loadKeys(ctx context.Context, refresh bool) (map[string]interface{}, error) {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil && !refresh {
+		return p.keys, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwkSet
+	if err := p.do(req, &set); err != nil {
+		return nil, fmt.Errorf("fetch oidc keys: %w", err)
+	}
+
+	keys, err := set.publicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("parse oidc keys: %w", err)
+	}
+
+	p.keys = keys
+	return p.keys, nil
+}
+
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned %d: %s", req.URL.Path, resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, v)
+}
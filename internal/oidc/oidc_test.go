@@ -0,0 +1,160 @@
+package oidc_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"goquizbox/internal/oidc"
+	"goquizbox/internal/oidc/oidctest"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func newProvider(t *testing.T, server *oidctest.Server) *oidc.Provider {
+	t.Helper()
+
+	registry, err := oidc.NewRegistry(oidc.Providers{{
+		Name:         "mock",
+		Issuer:       server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "http://localhost:8090/api/v1/auth/oidc/mock/callback",
+	}}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := registry.Provider("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// login runs the code flow against the mock server and returns the ID token
+// claims, or the first error.
+func login(t *testing.T, server *oidctest.Server, provider *oidc.Provider, nonce string) (*oidc.Claims, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, "the-state", "the-nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, state := server.Authorize(t, authURL, oidctest.Identity{
+		Subject:       "user-1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		GivenName:     "Jane",
+		FamilyName:    "Doe",
+	})
+	if state != "the-state" {
+		t.Fatalf("got state %q", state)
+	}
+
+	tokens, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+func TestCodeFlow(t *testing.T) {
+	t.Parallel()
+
+	server := oidctest.NewServer(t)
+	provider := newProvider(t, server)
+
+	claims, err := login(t, server, provider, "the-nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "user-1" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if claims.GivenName != "Jane" || claims.FamilyName != "Doe" {
+		t.Errorf("unexpected names %+v", claims)
+	}
+}
+
+func TestCodeFlowRejections(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		nonce  string
+		tamper func(jwt.MapClaims)
+	}{
+		{name: "nonce", nonce: "another-nonce"},
+		{name: "audience", nonce: "the-nonce", tamper: func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{name: "issuer", nonce: "the-nonce", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired", nonce: "the-nonce", tamper: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := oidctest.NewServer(t)
+			server.IDTokenClaims = tc.tamper
+			provider := newProvider(t, server)
+
+			if _, err := login(t, server, provider, tc.nonce); err == nil {
+				t.Error("expected the id token to be rejected")
+			}
+		})
+	}
+}
+
+func TestExchangeRequiresVerifier(t *testing.T) {
+	t.Parallel()
+
+	server := oidctest.NewServer(t)
+	provider := newProvider(t, server)
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(authURL, "code_challenge="+oidc.CodeChallenge(verifier)) {
+		t.Errorf("auth url %v is missing the code challenge", authURL)
+	}
+
+	code, _ := server.Authorize(t, authURL, oidctest.Identity{Subject: "user-1"})
+	if _, err := provider.Exchange(ctx, code, "wrong-verifier"); err == nil {
+		t.Error("exchange with the wrong code verifier succeeded")
+	}
+}
+
+func TestProvidersEnvDecode(t *testing.T) {
+	t.Parallel()
+
+	var providers oidc.Providers
+	if err := providers.EnvDecode(`[{"name":"google","issuer":"https://accounts.google.com","client_id":"id","redirect_url":"http://localhost/cb"}]`); err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 1 || providers[0].Name != "google" {
+		t.Errorf("unexpected providers %+v", providers)
+	}
+
+	if _, err := oidc.NewRegistry(oidc.Providers{{Name: "incomplete"}}, nil); err == nil {
+		t.Error("expected an error for an incomplete provider")
+	}
+}
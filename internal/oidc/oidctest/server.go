@@ -0,0 +1,206 @@
+// Package oidctest runs a minimal OpenID Connect provider for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const keyID = "oidctest"
+
+// Identity is what the server reports about the user who logs in.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+type grant struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	identity      Identity
+}
+
+// Server is a provider that issues codes through Authorize instead of a
+// login page.
+type Server struct {
+	*httptest.Server
+
+	ClientID     string
+	ClientSecret string
+
+	// IDTokenClaims lets tests tamper with the next ID tokens issued.
+	IDTokenClaims func(jwt.MapClaims)
+
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+	next   int
+}
+
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		key:          key,
+		grants:       make(map[string]grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+	mux.HandleFunc("/token", s.handleToken)
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Authorize plays the user logging in at the provider: it takes the URL the
+// client redirected to and returns the code the provider would send back.
+func (s *Server) Authorize(t *testing.T, authURL string, identity Identity) (code, state string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, authURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := req.URL.Query()
+
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request %v", authURL)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	code = fmt.Sprintf("code-%d", s.next)
+	s.grants[code] = grant{
+		clientID:      q.Get("client_id"),
+		redirectURI:   q.Get("redirect_uri"),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		identity:      identity,
+	}
+
+	return code, q.Get("state")
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, _ := r.BasicAuth()
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	g, ok := s.grants[r.PostForm.Get("code")]
+	delete(s.grants, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok || g.clientID != clientID || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            g.identity.Subject,
+		"aud":            clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          g.nonce,
+		"email":          g.identity.Email,
+		"email_verified": g.identity.EmailVerified,
+		"given_name":     g.identity.GivenName,
+		"family_name":    g.identity.FamilyName,
+	}
+	if s.IDTokenClaims != nil {
+		s.IDTokenClaims(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "access-" + g.identity.Subject,
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
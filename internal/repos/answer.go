@@ -14,10 +14,31 @@ import (
 )
 
 const (
-	createAnswerSQL = `insert into answers (user_id, question_id, body, created_at) values ($1, $2, $3, $4) returning id`
-	selectAnswerSQL = `select id, user_id, question_id, body, created_at, updated_at from answers`
-	countAnswerSQL  = `select count(id) from answers`
-	updateAnswerSQL = `update answers set (body, updated_at) = ($1, $2) where id=$3`
+	createAnswerSQL      = `insert into answers (user_id, question_id, body, created_at) values ($1, $2, $3, $4) returning id`
+	answerAcceptedSQL    = `coalesce(answers.id = (select accepted_answer_id from questions where questions.id = answers.question_id), false)`
+	answerColumnsSQL     = `id, user_id, question_id, body, ` + answerAcceptedSQL + ` as accepted, created_at, updated_at`
+	selectAnswerSQL      = `select ` + answerColumnsSQL + ` from answers`
+	getAnswerByIDSQL     = selectAnswerSQL + ` where id=$1`
+	countAnswerSQL       = `select count(id) from answers`
+	updateAnswerSQL      = `update answers set (body, updated_at) = ($1, $2) where id=$3`
+	deleteAnswerSQL      = `delete from answers where id=$1`
+	deleteAnswerVotesSQL = `delete from votes where kind = 'answer' and kind_id = $1`
+)
+
+// answerSorts are the orders answers can be listed in. The accepted answer
+// always comes first.
+var (
+	answerSorts = sortKeys{
+		"newest": {expr: "created_at", cast: "timestamptz", desc: true},
+		"oldest": {expr: "created_at", cast: "timestamptz"},
+		"votes": {
+			expr: `(select coalesce(sum(case when v.mode = 'up' then 1 else -1 end), 0) from votes v
+				where v.kind = 'answer' and v.kind_id = answers.id)`,
+			cast: "bigint",
+			desc: true,
+		},
+	}
+	acceptedSortKey = sortKey{name: "accepted", expr: answerAcceptedSQL, cast: "boolean", desc: true}
 )
 
 type AnswerDB struct {
@@ -42,7 +63,7 @@ func (a *AnswerDB) Save(ctx context.Context, m *entities.Answer) error {
 			if err != nil {
 				return fmt.Errorf("inserting answer: %w", err)
 			}
-			return nil
+			return insertRevision(ctx, tx, entities.NewAnswerRevision(m, m.UserID, ""))
 		}
 
 		_, err := tx.Exec(ctx, updateAnswerSQL, m.Body, m.UpdatedAt, m.ID)
@@ -53,19 +74,132 @@ func (a *AnswerDB) Save(ctx context.Context, m *entities.Answer) error {
 	})
 }
 
+// Edit updates an existing answer and records the new content as a revision
+// made by editorID.
+func (a *AnswerDB) Edit(
+	ctx context.Context,
+	m *entities.Answer,
+	editorID int64,
+	summary string,
+) (*entities.Revision, error) {
+	if errors := m.Validate(); len(errors) > 0 {
+		return nil, fmt.Errorf("AnswerDB invalid: %v", strings.Join(errors, ", "))
+	}
+
+	revision := entities.NewAnswerRevision(m, editorID, summary)
+	if err := a.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, updateAnswerSQL, m.Body, m.UpdatedAt, m.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update: %w", err)
+		}
+		return insertRevision(ctx, tx, revision)
+	}); err != nil {
+		return nil, fmt.Errorf("edit answer: %w", err)
+	}
+	return revision, nil
+}
+
+func (r *AnswerDB) ByID(ctx context.Context, id int64) (*entities.Answer, error) {
+	answer := entities.NewAnswer()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, getAnswerByIDSQL, id)
+
+		var err error
+		answer, err = r.scanOne(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get answer by id: %w", err)
+	}
+
+	return answer, nil
+}
+
+// Delete removes the answer along with its votes, revisions and comments.
+// Reputation earned through the answer is revoked.
+func (a *AnswerDB) Delete(ctx context.Context, id int64) error {
+	if err := a.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		revoked, err := revokePostReputation(ctx, tx, entities.PostKindAnswer, id)
+		if err != nil {
+			return err
+		}
+		if err := refreshReputation(ctx, tx, revoked); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, deleteAnswerVotesSQL, id); err != nil {
+			return fmt.Errorf("failed to delete votes: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deleteRevisionsSQL, entities.PostKindAnswer, id); err != nil {
+			return fmt.Errorf("failed to delete revisions: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deletePostCommentSQL, entities.PostKindAnswer, id); err != nil {
+			return fmt.Errorf("failed to delete comments: %w", err)
+		}
+		_, err = tx.Exec(ctx, deleteAnswerSQL, id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("delete answer by id: %w", err)
+	}
+	return nil
+}
+
+// ByQuestion returns a page of the question's answers, the accepted one
+// first. A search term with text in it orders the rest by relevance and
+// highlights the matches. In cursor mode it also returns the cursors around
+// the page.
 func (r *AnswerDB) ByQuestion(
 	ctx context.Context,
 	questionID int64,
 	filter *webutils.Filter,
-) ([]*entities.Answer, error) {
+	order *webutils.OrderFilter,
+) ([]*entities.Answer, *webutils.CursorPage, error) {
 	answers := make([]*entities.Answer, 0)
+	values := make([][]string, 0)
+
+	columns := answerColumnsSQL
+	sorts, defaultSort := answerSorts, "newest"
+	var tsquery string
+	if search := webutils.ParseSearch(filter.Term); search.HasText() {
+		// buildQuery hands the first placeholder to the question and the
+		// next ones to the tsquery, so it comes out the same here.
+		placeholder := util.NewPlaceholder()
+		placeholder.Touch()
+		tsquery, _ = tsQuery(search, placeholder.Touch)
+		columns += ", " + headlineSQL("body", tsquery, bodyHeadlineOptions)
+		sorts = sorts.with("relevance", tsRankSQL(tsquery))
+		defaultSort = "relevance"
+	}
+
+	sort, err := sorts.resolve(order, defaultSort)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list answers: %w", err)
+	}
+
+	ord := orderBy(acceptedSortKey, sort)
+	scanOrder, keys, err := newKeyset("answers", ord, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list answers: %w", err)
+	}
+
+	if filter.CursorMode {
+		columns += scanOrder.columns()
+	}
+	query := "select " + columns + " from answers"
 
 	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		query, args := r.buildQuery(
-			selectAnswerSQL,
+			query,
 			questionID,
 			filter,
+			keys,
 		)
+		query += " order by " + scanOrder.clause()
+
+		limit, args := limitClause(filter, args)
+		query += limit
 
 		rows, err := tx.Query(ctx, query, args...)
 		if err != nil {
@@ -78,19 +212,41 @@ func (r *AnswerDB) ByQuestion(
 				return fmt.Errorf("failed to iterate: %w", err)
 			}
 
-			answer, err := r.scanOne(rows)
+			highlight := &entities.AnswerHighlight{}
+			keyValues := make([]string, len(scanOrder))
+
+			extra := make([]interface{}, 0)
+			if tsquery != "" {
+				extra = append(extra, &highlight.Body)
+			}
+			if filter.CursorMode {
+				for i := range keyValues {
+					extra = append(extra, &keyValues[i])
+				}
+			}
+
+			answer, err := r.scanOne(rows, extra...)
 			if err != nil {
 				return fmt.Errorf("failed to parse: %w", err)
 			}
+			if tsquery != "" {
+				answer.Highlight = highlight
+			}
 			answers = append(answers, answer)
+			values = append(values, keyValues)
 		}
 
 		return nil
 	}); err != nil {
-		return nil, fmt.Errorf("list recipients: %w", err)
+		return nil, nil, fmt.Errorf("list answers: %w", err)
 	}
 
-	return answers, nil
+	if !filter.CursorMode {
+		return answers, nil, nil
+	}
+
+	answers, page := cursorPage("answers", ord, filter, answers, values)
+	return answers, page, nil
 }
 
 func (a *AnswerDB) CountByQuestion(
@@ -106,6 +262,7 @@ func (a *AnswerDB) CountByQuestion(
 			&webutils.Filter{
 				Term: filter.Term,
 			},
+			nil,
 		)
 		err := tx.QueryRow(ctx, query, args...).Scan(&count)
 		if err != nil {
@@ -123,6 +280,7 @@ func (r *AnswerDB) buildQuery(
 	query string,
 	messageID int64,
 	filter *webutils.Filter,
+	keys *keyset,
 ) (string, []interface{}) {
 	conditions := make([]string, 0)
 	args := make([]interface{}, 0)
@@ -131,35 +289,33 @@ func (r *AnswerDB) buildQuery(
 	conditions = append(conditions, fmt.Sprintf(" question_id=$%d", placeholder.Touch()))
 	args = append(args, messageID)
 
-	if filter.Term != "" {
-		likeStmt := make([]string, 0)
-		columns := []string{"body"}
-		for _, col := range columns {
-			search := fmt.Sprintf(" (lower(%s) like '%%' || $%d || '%%')", col, placeholder.Touch())
-			likeStmt = append(likeStmt, search)
-			args = append(args, filter.Term)
-		}
-		conditions = append(conditions, fmt.Sprintf("(%s)", strings.Join(likeStmt, " or")))
+	if search := webutils.ParseSearch(filter.Term); search.HasText() {
+		tsquery, tsargs := tsQuery(search, placeholder.Touch)
+		conditions = append(conditions, " "+tsMatchSQL(tsquery))
+		args = append(args, tsargs...)
 	}
 
-	if len(conditions) > 0 {
-		query += " where" + strings.Join(conditions, " and")
+	if keys != nil {
+		condition, keyArgs := keys.condition(placeholder.Touch)
+		conditions = append(conditions, condition)
+		args = append(args, keyArgs...)
 	}
 
-	if filter.Per > 0 && filter.Page > 0 {
-		query += fmt.Sprintf(" order by id desc limit $%d offset $%d", placeholder.Touch(), placeholder.Touch())
-		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	if len(conditions) > 0 {
+		query += " where" + strings.Join(conditions, " and")
 	}
 
 	return query, args
 }
 
-func (r *AnswerDB) scanOne(row pgx.Row) (*entities.Answer, error) {
+// scanOne reads an answer, followed by any extra columns into extra.
+func (r *AnswerDB) scanOne(row pgx.Row, extra ...interface{}) (*entities.Answer, error) {
 	answer := entities.NewAnswer()
 
-	if err := row.Scan(
-		&answer.ID, &answer.UserID, &answer.QuestionID, &answer.Body, &answer.CreatedAt, &answer.UpdatedAt,
-	); err != nil {
+	if err := row.Scan(append([]interface{}{
+		&answer.ID, &answer.UserID, &answer.QuestionID, &answer.Body, &answer.Accepted,
+		&answer.CreatedAt, &answer.UpdatedAt,
+	}, extra...)...); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
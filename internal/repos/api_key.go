@@ -0,0 +1,167 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/web/webutils"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	createApiKeySQL     = `insert into api_keys (user_id, name, username, secret_hash, scope, created_at) values ($1, $2, $3, $4, $5, $6) returning id`
+	updateApiKeySQL     = `update api_keys set name = $1, updated_at = $2 where id = $3`
+	selectApiKeysSQL    = `select id, user_id, name, username, secret_hash, scope, last_used_at, revoked_at, created_at, updated_at from api_keys`
+	getApiKeyByIDSQL    = selectApiKeysSQL + ` where id = $1`
+	getApiKeyByUserSQL  = selectApiKeysSQL + ` where username = $1`
+	revokeApiKeySQL     = `update api_keys set revoked_at = $1, updated_at = $1 where id = $2 and revoked_at is null`
+	touchApiKeySQL      = `update api_keys set last_used_at = $1 where id = $2 and (last_used_at is null or last_used_at < $1 - interval '1 minute')`
+	listApiKeysSQL      = selectApiKeysSQL + ` where user_id = $1 and revoked_at is null`
+	listApiKeysOrderSQL = ` order by id desc`
+)
+
+type ApiKeyDB struct {
+	db *database.DB
+}
+
+func NewApiKeyDB(db *database.DB) *ApiKeyDB {
+	return &ApiKeyDB{
+		db: db,
+	}
+}
+
+func (r *ApiKeyDB) Save(ctx context.Context, m *entities.ApiKey) error {
+	if errors := m.Validate(); len(errors) > 0 {
+		return fmt.Errorf("ApiKeyDB invalid: %v", strings.Join(errors, ", "))
+	}
+
+	m.Touch()
+	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if m.IsNew() {
+			err := tx.QueryRow(
+				ctx, createApiKeySQL, m.UserID, m.Name, m.Username, m.SecretHash, m.Scope, m.CreatedAt,
+			).Scan(&m.ID)
+			if err != nil {
+				return fmt.Errorf("inserting api key: %w", err)
+			}
+			return nil
+		}
+
+		_, err := tx.Exec(ctx, updateApiKeySQL, m.Name, m.UpdatedAt, m.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update api key: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *ApiKeyDB) ByID(ctx context.Context, id int64) (*entities.ApiKey, error) {
+	return r.get(ctx, getApiKeyByIDSQL, id)
+}
+
+func (r *ApiKeyDB) ByUsername(ctx context.Context, username string) (*entities.ApiKey, error) {
+	return r.get(ctx, getApiKeyByUserSQL, username)
+}
+
+// ByUser lists the user's keys that have not been revoked, optionally
+// narrowed to names containing filter.Name.
+func (r *ApiKeyDB) ByUser(ctx context.Context, userID int64, filter *webutils.ApiKeyFilter) ([]*entities.ApiKey, error) {
+	keys := make([]*entities.ApiKey, 0)
+
+	query := listApiKeysSQL
+	args := []interface{}{userID}
+	if filter.Name != "" {
+		query += " and " + containsSQL("lower(name)", 2)
+		args = append(args, escapeLike(filter.Name))
+	}
+	query += listApiKeysOrderSQL
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list api keys: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			key, err := r.scan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			keys = append(keys, key)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *ApiKeyDB) Revoke(ctx context.Context, id int64) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, revokeApiKeySQL, time.Now(), id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	return nil
+}
+
+// MarkUsed records that the key was just used. Writes are skipped if it was
+// already marked within the last minute.
+func (r *ApiKeyDB) MarkUsed(ctx context.Context, id int64) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, touchApiKeySQL, time.Now(), id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("mark api key used: %w", err)
+	}
+	return nil
+}
+
+func (r *ApiKeyDB) get(ctx context.Context, query string, arg interface{}) (*entities.ApiKey, error) {
+	key := entities.NewApiKey()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, query, arg)
+
+		var err error
+		key, err = r.scan(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (*ApiKeyDB) scan(row pgx.Row) (*entities.ApiKey, error) {
+	key := entities.NewApiKey()
+
+	if err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Username, &key.SecretHash, &key.Scope,
+		&key.LastUsedAt, &key.RevokedAt, &key.CreatedAt, &key.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
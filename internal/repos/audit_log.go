@@ -0,0 +1,39 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	createAuditLogSQL = `insert into audit_logs (user_id, action, ip_address, user_agent, details, created_at) values ($1, $2, $3, $4, $5, $6) returning id`
+)
+
+type AuditLogDB struct {
+	db *database.DB
+}
+
+func NewAuditLogDB(db *database.DB) *AuditLogDB {
+	return &AuditLogDB{
+		db: db,
+	}
+}
+
+func (r *AuditLogDB) Save(ctx context.Context, m *entities.AuditLog) error {
+	m.CreatedAt = time.Now()
+	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(
+			ctx, createAuditLogSQL, m.UserID, m.Action, m.IPAddress, m.UserAgent, m.Details, m.CreatedAt,
+		).Scan(&m.ID)
+		if err != nil {
+			return fmt.Errorf("inserting audit log: %w", err)
+		}
+		return nil
+	})
+}
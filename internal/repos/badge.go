@@ -0,0 +1,147 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/web/webutils"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	awardBadgeSQL          = `insert into user_badges (user_id, badge, context) select user_id, $1, context from (%s) q on conflict do nothing`
+	selectUserBadgesSQL    = `select id, user_id, badge, context, awarded_at from user_badges`
+	listBadgeHoldersSQL    = selectUserBadgesSQL + ` where badge = $1 order by awarded_at desc, id desc limit $2 offset $3`
+	countBadgeHoldersSQL   = `select count(id) from user_badges where badge = $1`
+	listUserBadgesSQL      = selectUserBadgesSQL + ` where user_id = $1 order by awarded_at desc, id desc`
+	countHoldersByBadgeSQL = `select badge, count(distinct user_id) from user_badges group by badge`
+)
+
+type BadgeDB struct {
+	db *database.DB
+}
+
+func NewBadgeDB(db *database.DB) *BadgeDB {
+	return &BadgeDB{
+		db: db,
+	}
+}
+
+// Award gives badge to every user returned by query, which must select
+// user_id and context columns. Users already holding the badge for a context
+// are skipped, so awarding is safe to repeat. It returns the number of new
+// awards.
+func (r *BadgeDB) Award(ctx context.Context, badge string, query string) (int64, error) {
+	var awarded int64
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, fmt.Sprintf(awardBadgeSQL, query), badge)
+		if err != nil {
+			return err
+		}
+		awarded = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("award badge %v: %w", badge, err)
+	}
+	return awarded, nil
+}
+
+// HolderCounts returns how many users hold each badge.
+func (r *BadgeDB) HolderCounts(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, countHoldersByBadgeSQL)
+		if err != nil {
+			return fmt.Errorf("failed to count badge holders: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var badge string
+			var count int
+			if err := rows.Scan(&badge, &count); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			counts[badge] = count
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("count badge holders: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *BadgeDB) Holders(ctx context.Context, badge string, filter *webutils.Filter) ([]*entities.UserBadge, error) {
+	return r.list(ctx, listBadgeHoldersSQL, badge, filter.Per, (filter.Page-1)*filter.Per)
+}
+
+func (r *BadgeDB) CountHolders(ctx context.Context, badge string) (*int, error) {
+	var count int
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countBadgeHoldersSQL, badge).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count badge holders: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count badge holders: %w", err)
+	}
+	return &count, nil
+}
+
+func (r *BadgeDB) ByUser(ctx context.Context, userID int64) ([]*entities.UserBadge, error) {
+	return r.list(ctx, listUserBadgesSQL, userID)
+}
+
+func (r *BadgeDB) list(ctx context.Context, query string, args ...interface{}) ([]*entities.UserBadge, error) {
+	badges := make([]*entities.UserBadge, 0)
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list user badges: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			badge, err := r.scan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			badges = append(badges, badge)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("list user badges: %w", err)
+	}
+
+	return badges, nil
+}
+
+func (*BadgeDB) scan(row pgx.Row) (*entities.UserBadge, error) {
+	badge := entities.NewUserBadge()
+
+	if err := row.Scan(
+		&badge.ID, &badge.UserID, &badge.Badge, &badge.Context, &badge.AwardedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return badge, nil
+}
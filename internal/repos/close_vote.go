@@ -0,0 +1,131 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	createCloseVoteSQL = `insert into close_votes (question_id, user_id, kind, reason, duplicate_of_id, created_at) values ($1, $2, $3, $4, $5, $6)
+		on conflict (question_id, user_id, kind) do nothing returning id`
+	countCloseVotesSQL = `select count(id) from close_votes where question_id = $1 and kind = $2`
+	clearCloseVotesSQL = `delete from close_votes where question_id = $1 and kind = $2`
+	lockQuestionSQL    = `select id from questions where id = $1 for update`
+	topCloseReasonSQL  = `select reason::text, (select duplicate_of_id from close_votes d
+			where d.question_id = $1 and d.kind = 'close' and d.reason = 'duplicate' and d.duplicate_of_id is not null
+			group by duplicate_of_id order by count(id) desc, min(id) limit 1)
+		from close_votes where question_id = $1 and kind = 'close'
+		group by reason order by count(id) desc, min(id) limit 1`
+	closeQuestionSQL  = `update questions set closed_at = now(), close_reason = $1, duplicate_of_id = $2 where id = $3`
+	reopenQuestionSQL = `update questions set closed_at = null, close_reason = null, duplicate_of_id = null where id = $1`
+)
+
+var ErrAlreadyVoted = errors.New("already voted")
+
+type CloseVoteDB struct {
+	db *database.DB
+}
+
+func NewCloseVoteDB(db *database.DB) *CloseVoteDB {
+	return &CloseVoteDB{
+		db: db,
+	}
+}
+
+// Cast records a close or reopen vote. Once required votes of the kind are
+// in, the question is closed or reopened and the votes are cleared. When
+// closing, the reason and duplicate target most voters chose win. It returns
+// the votes now standing and whether the question changed state.
+func (r *CloseVoteDB) Cast(ctx context.Context, m *entities.CloseVote, required int) (int, bool, error) {
+	if errors := m.Validate(); len(errors) > 0 {
+		return 0, false, fmt.Errorf("CloseVoteDB invalid: %v", strings.Join(errors, ", "))
+	}
+
+	var count int
+	var changed bool
+
+	m.CreatedAt = time.Now()
+	err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		// Serialize voting on the question so two final votes cannot both
+		// miss the threshold.
+		if _, err := tx.Exec(ctx, lockQuestionSQL, m.QuestionID); err != nil {
+			return fmt.Errorf("failed to lock question: %w", err)
+		}
+
+		err := tx.QueryRow(
+			ctx, createCloseVoteSQL, m.QuestionID, m.UserID, m.Kind, m.Reason, m.DuplicateOfID, m.CreatedAt,
+		).Scan(&m.ID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrAlreadyVoted
+			}
+			return fmt.Errorf("inserting close vote: %w", err)
+		}
+
+		if err := tx.QueryRow(ctx, countCloseVotesSQL, m.QuestionID, m.Kind).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count close votes: %w", err)
+		}
+
+		if count < required {
+			return nil
+		}
+
+		if m.Kind == entities.CloseVoteKindClose {
+			var reason string
+			var duplicateOfID *int64
+			if err := tx.QueryRow(ctx, topCloseReasonSQL, m.QuestionID).Scan(&reason, &duplicateOfID); err != nil {
+				return fmt.Errorf("failed to pick close reason: %w", err)
+			}
+
+			if reason != entities.CloseReasonDuplicate {
+				duplicateOfID = nil
+			}
+
+			if _, err := tx.Exec(ctx, closeQuestionSQL, reason, duplicateOfID, m.QuestionID); err != nil {
+				return fmt.Errorf("failed to close question: %w", err)
+			}
+		} else {
+			if _, err := tx.Exec(ctx, reopenQuestionSQL, m.QuestionID); err != nil {
+				return fmt.Errorf("failed to reopen question: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, clearCloseVotesSQL, m.QuestionID, m.Kind); err != nil {
+			return fmt.Errorf("failed to clear close votes: %w", err)
+		}
+
+		changed = true
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrAlreadyVoted) {
+			return 0, false, err
+		}
+		return 0, false, fmt.Errorf("cast close vote: %w", err)
+	}
+
+	return count, changed, nil
+}
+
+func (r *CloseVoteDB) Count(ctx context.Context, questionID int64, kind string) (*int, error) {
+	var count int
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countCloseVotesSQL, questionID, kind).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count close votes: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count close votes: %w", err)
+	}
+	return &count, nil
+}
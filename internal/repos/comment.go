@@ -0,0 +1,195 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/web/webutils"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	createCommentSQL     = `insert into comments (kind, kind_id, user_id, parent_id, body, created_at) values ($1, $2, $3, $4, $5, $6) returning id`
+	updateCommentSQL     = `update comments set (body, updated_at) = ($1, $2) where id = $3`
+	selectCommentsSQL    = `select id, kind, kind_id, user_id, parent_id, body, created_at, updated_at from comments`
+	getCommentByIDSQL    = selectCommentsSQL + ` where id = $1`
+	listCommentsSQL      = selectCommentsSQL + ` where kind = $1 and kind_id = $2 and parent_id is null order by id limit $3 offset $4`
+	listRepliesSQL       = selectCommentsSQL + ` where parent_id = any($1) order by id`
+	countCommentsSQL     = `select count(id) from comments where kind = $1 and kind_id = $2 and parent_id is null`
+	deleteCommentSQL     = `delete from comments where id = $1`
+	deletePostCommentSQL = `delete from comments where kind = $1 and kind_id = $2`
+	deleteAnswerComsSQL  = `delete from comments where kind = 'answer' and kind_id in (select id from answers where question_id = $1)`
+)
+
+type CommentDB struct {
+	db *database.DB
+}
+
+func NewCommentDB(db *database.DB) *CommentDB {
+	return &CommentDB{
+		db: db,
+	}
+}
+
+func (r *CommentDB) Save(ctx context.Context, m *entities.Comment) error {
+	if errors := m.Validate(); len(errors) > 0 {
+		return fmt.Errorf("CommentDB invalid: %v", strings.Join(errors, ", "))
+	}
+
+	m.Touch()
+	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if m.IsNew() {
+			err := tx.QueryRow(
+				ctx, createCommentSQL, m.Kind, m.KindID, m.UserID, m.ParentID, m.Body, m.CreatedAt,
+			).Scan(&m.ID)
+			if err != nil {
+				return fmt.Errorf("inserting comment: %w", err)
+			}
+			return nil
+		}
+
+		_, err := tx.Exec(ctx, updateCommentSQL, m.Body, m.UpdatedAt, m.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update comment: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *CommentDB) ByID(ctx context.Context, id int64) (*entities.Comment, error) {
+	comment := entities.NewComment()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, getCommentByIDSQL, id)
+
+		var err error
+		comment, err = r.scan(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get comment by id: %w", err)
+	}
+
+	return comment, nil
+}
+
+// ByPost returns a page of top level comments on a post, oldest first, each
+// with all of its replies attached.
+func (r *CommentDB) ByPost(
+	ctx context.Context,
+	kind string,
+	kindID int64,
+	filter *webutils.Filter,
+) ([]*entities.Comment, error) {
+	comments := make([]*entities.Comment, 0)
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var err error
+		comments, err = r.list(ctx, tx, listCommentsSQL, kind, kindID, filter.Per, (filter.Page-1)*filter.Per)
+		if err != nil {
+			return fmt.Errorf("failed to list comments: %w", err)
+		}
+
+		if len(comments) == 0 {
+			return nil
+		}
+
+		parents := make(map[int64]*entities.Comment, len(comments))
+		parentIDs := make([]int64, 0, len(comments))
+		for _, comment := range comments {
+			comment.Replies = make([]*entities.Comment, 0)
+			parents[comment.ID] = comment
+			parentIDs = append(parentIDs, comment.ID)
+		}
+
+		replies, err := r.list(ctx, tx, listRepliesSQL, parentIDs)
+		if err != nil {
+			return fmt.Errorf("failed to list replies: %w", err)
+		}
+
+		for _, reply := range replies {
+			if parent, ok := parents[reply.ParentID.Int64]; ok {
+				parent.Replies = append(parent.Replies, reply)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list comments: %w", err)
+	}
+
+	return comments, nil
+}
+
+func (r *CommentDB) CountByPost(ctx context.Context, kind string, kindID int64) (*int, error) {
+	var count int
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countCommentsSQL, kind, kindID).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count comments: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count comments: %w", err)
+	}
+	return &count, nil
+}
+
+// Delete removes a comment, replies to it are removed by the database.
+func (r *CommentDB) Delete(ctx context.Context, id int64) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, deleteCommentSQL, id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("delete comment by id: %w", err)
+	}
+	return nil
+}
+
+func (r *CommentDB) list(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]*entities.Comment, error) {
+	comments := make([]*entities.Comment, 0)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to iterate: %w", err)
+		}
+
+		comment, err := r.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (*CommentDB) scan(row pgx.Row) (*entities.Comment, error) {
+	comment := entities.NewComment()
+
+	if err := row.Scan(
+		&comment.ID, &comment.Kind, &comment.KindID, &comment.UserID, &comment.ParentID,
+		&comment.Body, &comment.CreatedAt, &comment.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
@@ -0,0 +1,158 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/web/webutils"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	// refreshQuestionScoresSQL recomputes the hot and trending scores as of
+	// $1. Hot scores decay with time so they are rounded to $6 decimal
+	// places; only questions whose rounded scores moved are written, which
+	// each sweep is the recently active ones rather than the whole table.
+	refreshQuestionScoresSQL = `with net_votes as (
+			select kind_id as question_id, sum(case when mode = 'up' then 1 else -1 end) as total,
+				sum(case when coalesce(updated_at, created_at) >= $5 then (case when mode = 'up' then 1 else -1 end) else 0 end) as recent
+			from votes where kind = 'question' group by kind_id
+		), answer_counts as (
+			select question_id, count(*) as total, count(*) filter (where created_at >= $5) as recent
+			from answers group by question_id
+		), scores as (
+			select q.id,
+				round(((greatest(coalesce(v.total, 0) + $3 * coalesce(a.total, 0) + q.view_count / $4::double precision, 0) + 1)
+					/ power(greatest(extract(epoch from ($1 - q.created_at)) / 3600, 0) + 2, $2))::numeric, $6::int)::double precision as hot,
+				greatest(coalesce(v.recent, 0) + $3 * coalesce(a.recent, 0), 0) as trending
+			from questions q
+			left join net_votes v on v.question_id = q.id
+			left join answer_counts a on a.question_id = q.id
+		)
+		update questions set hot_score = scores.hot, trending_score = scores.trending, scored_at = $1
+		from scores
+		where questions.id = scores.id
+			and (questions.hot_score, questions.trending_score) is distinct from (scores.hot, scores.trending)`
+	unansweredConditionSQL = `closed_at is null and accepted_answer_id is null and not exists (
+		select 1 from answers a join votes v on v.kind = 'answer' and v.kind_id = a.id and v.mode = 'up'
+		where a.question_id = questions.id)`
+	myTagsConditionSQL = `user_id <> $1 and exists (
+		select 1 from question_tags qt where qt.question_id = questions.id and qt.tag_id in (
+			select mine.tag_id from question_tags mine join questions q on q.id = mine.question_id
+			where q.user_id = $1 or q.id in (select question_id from answers where user_id = $1)))`
+)
+
+// questionFeed is the condition and order of a feed. Conditions of user
+// feeds take the user's id as $1.
+type questionFeed struct {
+	condition string
+	order     string
+	forUser   bool
+}
+
+var questionFeeds = map[string]questionFeed{
+	entities.FeedHot:        {condition: "closed_at is null", order: "hot_score desc, id desc"},
+	entities.FeedTrending:   {condition: "trending_score > 0", order: "trending_score desc, id desc"},
+	entities.FeedUnanswered: {condition: unansweredConditionSQL, order: "created_at desc, id desc"},
+	entities.FeedMyTags:     {condition: myTagsConditionSQL, order: "created_at desc, id desc", forUser: true},
+}
+
+// RefreshScores recomputes the hot and trending scores of every question as
+// of now and returns how many changed.
+func (q *QuestionDB) RefreshScores(ctx context.Context, now time.Time) (int64, error) {
+	var updated int64
+	if err := q.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(
+			ctx, refreshQuestionScoresSQL, now, entities.HotGravity, entities.HotAnswerPoints,
+			entities.HotViewsPerPoint, now.Add(-entities.TrendingWindow), entities.HotScorePrecision,
+		)
+		if err != nil {
+			return err
+		}
+		updated = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("refresh question scores: %w", err)
+	}
+	return updated, nil
+}
+
+// Feed returns a page of the named feed. userID is only used by feeds
+// built for a user.
+func (q *QuestionDB) Feed(
+	ctx context.Context,
+	name string,
+	userID int64,
+	filter *webutils.Filter,
+) ([]*entities.Question, error) {
+	questions := make([]*entities.Question, 0)
+
+	query, args, err := q.feedQuery(getQuestionsSQL, name, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query += " order by " + questionFeeds[name].order
+	limit, args := limitClause(filter, args)
+	query += limit
+
+	if err := q.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list feed: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			question, err := q.scan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			questions = append(questions, question)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list %v feed: %w", name, err)
+	}
+
+	return questions, nil
+}
+
+func (q *QuestionDB) CountFeed(ctx context.Context, name string, userID int64) (*int, error) {
+	query, args, err := q.feedQuery(countCuestionsSQL, name, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := q.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count feed: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count %v feed: %w", name, err)
+	}
+	return &count, nil
+}
+
+func (q *QuestionDB) feedQuery(query, name string, userID int64) (string, []interface{}, error) {
+	feed, ok := questionFeeds[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown question feed %q", name)
+	}
+
+	args := make([]interface{}, 0)
+	if feed.forUser {
+		args = append(args, userID)
+	}
+	return query + " where " + feed.condition, args, nil
+}
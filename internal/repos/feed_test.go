@@ -0,0 +1,54 @@
+package repos
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"goquizbox/internal/entities"
+)
+
+func TestFeedQuery(t *testing.T) {
+	testCases := []struct {
+		feed      string
+		condition string
+		order     string
+		args      []interface{}
+	}{
+		{feed: entities.FeedHot, condition: "closed_at is null", order: "hot_score desc, id desc", args: []interface{}{}},
+		{feed: entities.FeedTrending, condition: "trending_score > 0", order: "trending_score desc, id desc", args: []interface{}{}},
+		{feed: entities.FeedUnanswered, condition: "accepted_answer_id is null", order: "created_at desc, id desc", args: []interface{}{}},
+		{feed: entities.FeedMyTags, condition: "user_id <> $1", order: "created_at desc, id desc", args: []interface{}{int64(7)}},
+	}
+
+	db := &QuestionDB{}
+	for _, tc := range testCases {
+		t.Run(tc.feed, func(t *testing.T) {
+			query, args, err := db.feedQuery(countCuestionsSQL, tc.feed, 7)
+			if err != nil {
+				t.Fatalf("feedQuery: %v", err)
+			}
+			if !strings.HasPrefix(query, countCuestionsSQL+" where ") || !strings.Contains(query, tc.condition) {
+				t.Errorf("got query %v, want it filtered on %v", query, tc.condition)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("got args %v, want %v", args, tc.args)
+			}
+			if got := questionFeeds[tc.feed].order; got != tc.order {
+				t.Errorf("got order %v, want %v", got, tc.order)
+			}
+		})
+	}
+
+	if _, _, err := db.feedQuery(countCuestionsSQL, "cold", 7); err == nil {
+		t.Error("expected unknown feed to be rejected")
+	}
+}
+
+func TestUnansweredFeedExcludes(t *testing.T) {
+	for _, condition := range []string{"closed_at is null", "accepted_answer_id is null", "v.mode = 'up'"} {
+		if !strings.Contains(unansweredConditionSQL, condition) {
+			t.Errorf("expected unanswered feed to require %v", condition)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+
+	pgx "github.com/jackc/pgx/v4"
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	createLoginAttemptSQL = `insert into login_attempts (email, ip_address, success, created_at) values ($1, $2, $3, $4) returning id`
+	// An account's failures are forgiven by a successful login. An IP's are
+	// not, otherwise logging into an account of your own would reset them.
+	emailLoginFailuresSQL = `select count(id), max(created_at) from login_attempts
+		where email = $1 and not success and created_at > greatest($2,
+			coalesce((select max(created_at) from login_attempts where email = $1 and success), $2))`
+	ipLoginFailuresSQL = `select count(id), max(created_at) from login_attempts
+		where ip_address = $1 and not success and created_at > $2`
+)
+
+type LoginAttemptDB struct {
+	db *database.DB
+}
+
+func NewLoginAttemptDB(db *database.DB) *LoginAttemptDB {
+	return &LoginAttemptDB{
+		db: db,
+	}
+}
+
+func (r *LoginAttemptDB) Save(ctx context.Context, m *entities.LoginAttempt) error {
+	m.CreatedAt = time.Now()
+	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, createLoginAttemptSQL, m.Email, m.IPAddress, m.Success, m.CreatedAt).Scan(&m.ID)
+		if err != nil {
+			return fmt.Errorf("inserting login attempt: %w", err)
+		}
+		return nil
+	})
+}
+
+// Failures returns the failed attempts for email since its last successful
+// login and for ipAddress, looking no further back than since.
+func (r *LoginAttemptDB) Failures(ctx context.Context, email, ipAddress string, since time.Time) (*entities.LoginFailures, *entities.LoginFailures, error) {
+	emailFailures := &entities.LoginFailures{}
+	ipFailures := &entities.LoginFailures{}
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := scanLoginFailures(tx.QueryRow(ctx, emailLoginFailuresSQL, email, since), emailFailures); err != nil {
+			return fmt.Errorf("failed to count email failures: %w", err)
+		}
+		if err := scanLoginFailures(tx.QueryRow(ctx, ipLoginFailuresSQL, ipAddress, since), ipFailures); err != nil {
+			return fmt.Errorf("failed to count ip failures: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("login failures: %w", err)
+	}
+
+	return emailFailures, ipFailures, nil
+}
+
+func scanLoginFailures(row pgx.Row, failures *entities.LoginFailures) error {
+	var last null.Time
+	if err := row.Scan(&failures.Count, &last); err != nil {
+		return err
+	}
+	failures.LastFailure = last.Time
+	return nil
+}
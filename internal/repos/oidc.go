@@ -0,0 +1,168 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	createOIDCLoginSQL      = `insert into oidc_logins (user_id, provider, state_hash, nonce, code_verifier, expires_at, created_at) values ($1, $2, $3, $4, $5, $6, $7) returning id`
+	useOIDCLoginSQL         = `update oidc_logins set used_at = $1 where provider = $2 and state_hash = $3 and used_at is null and expires_at > $1 returning id, user_id, provider, state_hash, nonce, code_verifier, expires_at, used_at, created_at`
+	deleteStaleOIDCLoginSQL = `delete from oidc_logins where expires_at < $1`
+	createUserIdentitySQL   = `insert into user_identities (user_id, provider, subject, email, created_at) values ($1, $2, $3, $4, $5) returning id`
+	getUserIdentitySQL      = `select id, user_id, provider, subject, email, created_at from user_identities where provider = $1 and subject = $2`
+)
+
+type OIDCDB struct {
+	db *database.DB
+}
+
+func NewOIDCDB(db *database.DB) *OIDCDB {
+	return &OIDCDB{
+		db: db,
+	}
+}
+
+// CreateLogin stores a started login, clearing out expired ones.
+func (r *OIDCDB) CreateLogin(ctx context.Context, m *entities.OIDCLogin) error {
+	m.CreatedAt = time.Now()
+	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, deleteStaleOIDCLoginSQL, m.CreatedAt); err != nil {
+			return fmt.Errorf("failed to delete stale oidc logins: %w", err)
+		}
+
+		err := tx.QueryRow(
+			ctx, createOIDCLoginSQL, m.UserID, m.Provider, m.StateHash, m.Nonce, m.CodeVerifier, m.ExpiresAt, m.CreatedAt,
+		).Scan(&m.ID)
+		if err != nil {
+			return fmt.Errorf("inserting oidc login: %w", err)
+		}
+		return nil
+	})
+}
+
+// UseLogin spends the login for provider with stateHash. A nil result means
+// there is no such unused, unexpired login.
+func (r *OIDCDB) UseLogin(ctx context.Context, provider, stateHash string) (*entities.OIDCLogin, error) {
+	login := entities.NewOIDCLogin()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, useOIDCLoginSQL, time.Now(), provider, stateHash)
+
+		var err error
+		login, err = r.scanLogin(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("use oidc login: %w", err)
+	}
+
+	return login, nil
+}
+
+func (r *OIDCDB) IdentityBySubject(ctx context.Context, provider, subject string) (*entities.UserIdentity, error) {
+	identity := entities.NewUserIdentity()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, getUserIdentitySQL, provider, subject)
+
+		var err error
+		identity, err = r.scanIdentity(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get user identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// LinkIdentity attaches the identity to an existing user.
+func (r *OIDCDB) LinkIdentity(ctx context.Context, m *entities.UserIdentity) error {
+	m.CreatedAt = time.Now()
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		return r.insertIdentity(ctx, tx, m)
+	}); err != nil {
+		return fmt.Errorf("link user identity: %w", err)
+	}
+	return nil
+}
+
+// CreateUserWithIdentity registers a new user together with the identity
+// they logged in with.
+func (r *OIDCDB) CreateUserWithIdentity(ctx context.Context, user *entities.User, m *entities.UserIdentity) error {
+	if errors := user.Validate(); len(errors) > 0 {
+		return fmt.Errorf("UserDB invalid: %v", strings.Join(errors, ", "))
+	}
+
+	user.Touch()
+	m.CreatedAt = user.CreatedAt
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(
+			ctx, createUserSQL, user.FirstName, user.LastName, user.Email, user.EmailActivationKey,
+			user.EmailActivationAt, user.EmailVerified, user.Status, user.PasswordHash, user.CreatedAt,
+		).Scan(&user.ID)
+		if err != nil {
+			return fmt.Errorf("inserting user: %w", err)
+		}
+
+		m.UserID = user.ID
+		return r.insertIdentity(ctx, tx, m)
+	}); err != nil {
+		return fmt.Errorf("create user with identity: %w", err)
+	}
+	return nil
+}
+
+func (r *OIDCDB) insertIdentity(ctx context.Context, tx pgx.Tx, m *entities.UserIdentity) error {
+	err := tx.QueryRow(
+		ctx, createUserIdentitySQL, m.UserID, m.Provider, m.Subject, m.Email, m.CreatedAt,
+	).Scan(&m.ID)
+	if err != nil {
+		return fmt.Errorf("inserting user identity: %w", err)
+	}
+	return nil
+}
+
+func (*OIDCDB) scanLogin(row pgx.Row) (*entities.OIDCLogin, error) {
+	login := entities.NewOIDCLogin()
+
+	if err := row.Scan(
+		&login.ID, &login.UserID, &login.Provider, &login.StateHash, &login.Nonce, &login.CodeVerifier,
+		&login.ExpiresAt, &login.UsedAt, &login.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return login, nil
+}
+
+func (*OIDCDB) scanIdentity(row pgx.Row) (*entities.UserIdentity, error) {
+	identity := entities.NewUserIdentity()
+
+	if err := row.Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,114 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	createPasswordResetSQL  = `insert into password_resets (user_id, token_hash, ip_address, expires_at, created_at) values ($1, $2, $3, $4, $5) returning id`
+	retirePasswordResetsSQL = `update password_resets set used_at = $1 where user_id = $2 and used_at is null`
+	getPasswordResetSQL     = `select id, user_id, token_hash, ip_address, expires_at, used_at, created_at from password_resets where token_hash = $1`
+	usePasswordResetSQL     = `update password_resets set used_at = $1 where id = $2 and used_at is null and expires_at > $1`
+)
+
+var ErrPasswordResetUsed = errors.New("password reset already used or expired")
+
+type PasswordResetDB struct {
+	db *database.DB
+}
+
+func NewPasswordResetDB(db *database.DB) *PasswordResetDB {
+	return &PasswordResetDB{
+		db: db,
+	}
+}
+
+// Create stores a new reset, retiring any the user has outstanding so only
+// the latest one works.
+func (r *PasswordResetDB) Create(ctx context.Context, m *entities.PasswordReset) error {
+	m.CreatedAt = time.Now()
+	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, retirePasswordResetsSQL, m.CreatedAt, m.UserID); err != nil {
+			return fmt.Errorf("failed to retire password resets: %w", err)
+		}
+
+		err := tx.QueryRow(
+			ctx, createPasswordResetSQL, m.UserID, m.TokenHash, m.IPAddress, m.ExpiresAt, m.CreatedAt,
+		).Scan(&m.ID)
+		if err != nil {
+			return fmt.Errorf("inserting password reset: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *PasswordResetDB) ByTokenHash(ctx context.Context, tokenHash string) (*entities.PasswordReset, error) {
+	reset := entities.NewPasswordReset()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, getPasswordResetSQL, tokenHash)
+
+		var err error
+		reset, err = r.scan(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get password reset: %w", err)
+	}
+
+	return reset, nil
+}
+
+// Use spends the reset, sets the user's new password and deactivates all of
+// their sessions. It fails with ErrPasswordResetUsed if the reset was spent
+// or expired in the meantime.
+func (r *PasswordResetDB) Use(ctx context.Context, m *entities.PasswordReset, passwordHash string) error {
+	now := time.Now()
+	err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, usePasswordResetSQL, now, m.ID)
+		if err != nil {
+			return fmt.Errorf("failed to use password reset: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrPasswordResetUsed
+		}
+
+		return setPassword(ctx, tx, m.UserID, passwordHash, 0, now)
+	})
+	if err != nil {
+		if errors.Is(err, ErrPasswordResetUsed) {
+			return err
+		}
+		return fmt.Errorf("use password reset: %w", err)
+	}
+
+	m.UsedAt.SetValid(now)
+	return nil
+}
+
+func (*PasswordResetDB) scan(row pgx.Row) (*entities.PasswordReset, error) {
+	reset := entities.NewPasswordReset()
+
+	if err := row.Scan(
+		&reset.ID, &reset.UserID, &reset.TokenHash, &reset.IPAddress, &reset.ExpiresAt,
+		&reset.UsedAt, &reset.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return reset, nil
+}
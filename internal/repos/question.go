@@ -12,17 +12,45 @@ import (
 	"goquizbox/internal/web/webutils"
 
 	pgx "github.com/jackc/pgx/v4"
+	null "gopkg.in/guregu/null.v4"
 )
 
 const (
-	createQuestionSQL  = `insert into questions (user_id, title, body, tags, created_at) values ($1, $2, $3, $4, $5) returning id`
-	updateQuestionSQL  = `update questions set title=$1, body=$2, tags=$3, updated_at=$4 where id = $5`
-	getQuestionsSQL    = `select id, user_id, title, body, tags, created_at, updated_at from questions`
-	getQuestionByIDSQL = getQuestionsSQL + ` where id=$1`
-	countCuestionsSQL  = "select count(id) from questions"
-	deleteQuestionSQL  = `delete from questions where id=$1`
+	createQuestionSQL  = `insert into questions (user_id, title, body, created_at) values ($1, $2, $3, $4) returning id`
+	updateQuestionSQL  = `update questions set title=$1, body=$2, updated_at=$3 where id = $4`
+	questionColumnsSQL = `id, user_id, title, body,
+		array(select t.name from question_tags qt join tags t on t.id = qt.tag_id where qt.question_id = questions.id order by t.name) as tags,
+		accepted_answer_id, closed_at, close_reason, duplicate_of_id, view_count, created_at, updated_at`
+	getQuestionsSQL  = `select ` + questionColumnsSQL + ` from questions`
+	questionScoreSQL = `(select coalesce(sum(case when v.mode = 'up' then 1 else -1 end), 0) from votes v
+		where v.kind = 'question' and v.kind_id = questions.id)`
+	getQuestionByIDSQL     = getQuestionsSQL + ` where id=$1`
+	countCuestionsSQL      = "select count(id) from questions"
+	deleteQuestionSQL      = `delete from questions where id=$1`
+	deleteQuestionVotesSQL = `delete from votes where (kind = 'question' and kind_id = $1)
+		or (kind = 'answer' and kind_id in (select id from answers where question_id = $1))`
+	acceptAnswerSQL       = `update questions set accepted_answer_id=$1 where id=$2`
+	lockAcceptedAnswerSQL = `select accepted_answer_id, user_id from questions where id=$1 for update`
+	addQuestionViewsSQL   = `update questions set view_count = view_count + v.views
+		from unnest($1::bigint[], $2::bigint[]) as v(id, views)
+		where questions.id = v.id`
 )
 
+// questionSorts are the orders questions can be listed in. Searches can
+// also be ordered by relevance, which is their default.
+var questionSorts = sortKeys{
+	"newest": {expr: "created_at", cast: "timestamptz", desc: true},
+	"active": {
+		expr: `greatest(created_at, updated_at,
+			(select max(coalesce(a.updated_at, a.created_at)) from answers a where a.question_id = questions.id))`,
+		cast: "timestamptz",
+		desc: true,
+	},
+	"votes":   {expr: questionScoreSQL, cast: "bigint", desc: true},
+	"answers": {expr: "(select count(*) from answers a where a.question_id = questions.id)", cast: "bigint", desc: true},
+	"views":   {expr: "view_count", cast: "bigint", desc: true},
+}
+
 type QuestionDB struct {
 	db *database.DB
 }
@@ -33,14 +61,58 @@ func NewQuestionDB(db *database.DB) *QuestionDB {
 	}
 }
 
-func (r *QuestionDB) List(ctx context.Context, filter *webutils.Filter) ([]*entities.Question, error) {
+// List returns a page of the questions matching filter, newest first unless
+// order says otherwise. A search term with text in it orders the questions
+// by relevance and highlights the matches. In cursor mode it also returns
+// the cursors around the page.
+func (r *QuestionDB) List(
+	ctx context.Context,
+	filter *webutils.Filter,
+	order *webutils.OrderFilter,
+) ([]*entities.Question, *webutils.CursorPage, error) {
 	questions := make([]*entities.Question, 0)
+	values := make([][]string, 0)
+
+	search := webutils.ParseSearch(filter.Term)
+
+	columns := questionColumnsSQL
+	sorts, defaultSort := questionSorts, "newest"
+	var tsquery string
+	if search.HasText() {
+		// buildQuery hands out the first placeholders to the tsquery, so it
+		// comes out the same here.
+		tsquery, _ = tsQuery(search, util.NewPlaceholder().Touch)
+		columns += ", " + headlineSQL("title", tsquery, titleHeadlineOptions) +
+			", " + headlineSQL("body", tsquery, bodyHeadlineOptions)
+		sorts = sorts.with("relevance", tsRankSQL(tsquery))
+		defaultSort = "relevance"
+	}
+
+	sort, err := sorts.resolve(order, defaultSort)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list questions: %w", err)
+	}
+
+	ord := orderBy(sort)
+	scanOrder, keys, err := newKeyset("questions", ord, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list questions: %w", err)
+	}
+
+	if filter.CursorMode {
+		columns += scanOrder.columns()
+	}
 
 	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		query, args := r.buildQuery(
-			getQuestionsSQL,
+			"select "+columns+" from questions",
 			filter,
+			keys,
 		)
+		query += " order by " + scanOrder.clause()
+
+		limit, args := limitClause(filter, args)
+		query += limit
 
 		rows, err := tx.Query(ctx, query, args...)
 		if err != nil {
@@ -53,19 +125,41 @@ func (r *QuestionDB) List(ctx context.Context, filter *webutils.Filter) ([]*enti
 				return fmt.Errorf("failed to iterate: %w", err)
 			}
 
-			question, err := r.scan(rows)
+			highlight := &entities.QuestionHighlight{}
+			keyValues := make([]string, len(scanOrder))
+
+			extra := make([]interface{}, 0)
+			if tsquery != "" {
+				extra = append(extra, &highlight.Title, &highlight.Body)
+			}
+			if filter.CursorMode {
+				for i := range keyValues {
+					extra = append(extra, &keyValues[i])
+				}
+			}
+
+			question, err := r.scan(rows, extra...)
 			if err != nil {
 				return fmt.Errorf("failed to parse: %w", err)
 			}
+			if tsquery != "" {
+				question.Highlight = highlight
+			}
 			questions = append(questions, question)
+			values = append(values, keyValues)
 		}
 
 		return nil
 	}); err != nil {
-		return nil, fmt.Errorf("list questions: %w", err)
+		return nil, nil, fmt.Errorf("list questions: %w", err)
+	}
+
+	if !filter.CursorMode {
+		return questions, nil, nil
 	}
 
-	return questions, nil
+	questions, page := cursorPage("questions", ord, filter, questions, values)
+	return questions, page, nil
 }
 
 func (q *QuestionDB) Count(ctx context.Context, filter *webutils.Filter) (*int, error) {
@@ -73,9 +167,8 @@ func (q *QuestionDB) Count(ctx context.Context, filter *webutils.Filter) (*int,
 	if err := q.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		query, args := q.buildQuery(
 			countCuestionsSQL,
-			&webutils.Filter{
-				Term: filter.Term,
-			},
+			filter.NoPagination(),
+			nil,
 		)
 		err := tx.QueryRow(ctx, query, args...).Scan(&count)
 		if err != nil {
@@ -98,23 +191,56 @@ func (u *QuestionDB) Save(ctx context.Context, m *entities.Question) error {
 	return u.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		if m.IsNew() {
 			err := tx.QueryRow(
-				ctx, createQuestionSQL, m.UserID, m.Title, m.Body, m.Tags, m.CreatedAt,
+				ctx, createQuestionSQL, m.UserID, m.Title, m.Body, m.CreatedAt,
 			).Scan(&m.ID)
 			if err != nil {
 				return fmt.Errorf("inserting question: %w", err)
 			}
-			return nil
+			if err := saveQuestionTags(ctx, tx, m.ID, m.Tags); err != nil {
+				return err
+			}
+			return insertRevision(ctx, tx, entities.NewQuestionRevision(m, m.UserID, ""))
 		}
 		_, err := tx.Exec(
-			ctx, updateQuestionSQL, m.Title, m.Body, m.Tags, m.UpdatedAt, m.ID,
+			ctx, updateQuestionSQL, m.Title, m.Body, m.UpdatedAt, m.ID,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to update question: %w", err)
 		}
-		return nil
+		return saveQuestionTags(ctx, tx, m.ID, m.Tags)
 	})
 }
 
+// Edit updates an existing question and records the new content as a
+// revision made by editorID.
+func (u *QuestionDB) Edit(
+	ctx context.Context,
+	m *entities.Question,
+	editorID int64,
+	summary string,
+) (*entities.Revision, error) {
+	if errors := m.Validate(); len(errors) > 0 {
+		return nil, fmt.Errorf("QuestionDB invalid: %v", strings.Join(errors, ", "))
+	}
+
+	revision := entities.NewQuestionRevision(m, editorID, summary)
+	if err := u.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(
+			ctx, updateQuestionSQL, m.Title, m.Body, m.UpdatedAt, m.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update question: %w", err)
+		}
+		if err := saveQuestionTags(ctx, tx, m.ID, m.Tags); err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, revision)
+	}); err != nil {
+		return nil, fmt.Errorf("edit question: %w", err)
+	}
+	return revision, nil
+}
+
 func (r *QuestionDB) ByID(ctx context.Context, id int64) (*entities.Question, error) {
 	question := entities.NewQuestion()
 
@@ -134,9 +260,89 @@ func (r *QuestionDB) ByID(ctx context.Context, id int64) (*entities.Question, er
 	return question, nil
 }
 
+// SetAcceptedAnswer marks answerID as the solution to the question, an
+// invalid answerID clears the accepted answer. Reputation for accepting moves
+// from the previously accepted answer to the new one.
+func (q *QuestionDB) SetAcceptedAnswer(ctx context.Context, questionID int64, answerID null.Int) error {
+	if err := q.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var previousID, questionOwnerID null.Int
+		if err := tx.QueryRow(ctx, lockAcceptedAnswerSQL, questionID).Scan(&previousID, &questionOwnerID); err != nil {
+			return fmt.Errorf("failed to get accepted answer: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, acceptAnswerSQL, answerID, questionID); err != nil {
+			return err
+		}
+
+		if previousID == answerID || !questionOwnerID.Valid {
+			return nil
+		}
+
+		userIDs := make([]int64, 0)
+		if previousID.Valid {
+			revoked, err := revokeReputation(
+				ctx, tx, entities.PostKindAnswer, previousID.Int64, questionOwnerID.Int64, entities.AcceptAwards(),
+			)
+			if err != nil {
+				return err
+			}
+			userIDs = append(userIDs, revoked...)
+		}
+
+		if answerID.Valid {
+			answerOwnerID, err := postOwner(ctx, tx, entities.PostKindAnswer, answerID.Int64)
+			if err != nil {
+				return err
+			}
+
+			awarded, err := awardReputation(
+				ctx, tx, entities.PostKindAnswer, answerID.Int64, answerOwnerID, questionOwnerID.Int64, entities.AcceptAwards(),
+			)
+			if err != nil {
+				return err
+			}
+			userIDs = append(userIDs, awarded...)
+		}
+
+		return refreshReputation(ctx, tx, userIDs)
+	}); err != nil {
+		return fmt.Errorf("set accepted answer: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the question along with its answers and any votes,
+// revisions and comments belonging to either. Reputation earned through them
+// is revoked.
 func (q *QuestionDB) Delete(ctx context.Context, id int64) error {
 	if err := q.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		_, err := tx.Exec(ctx, deleteQuestionSQL, id)
+		revoked, err := revokePostReputation(ctx, tx, entities.PostKindQuestion, id)
+		if err != nil {
+			return err
+		}
+		answersRevoked, err := collectUserIDs(tx.Query(ctx, revokeAnswersReputationSQL, id))
+		if err != nil {
+			return err
+		}
+		if err := refreshReputation(ctx, tx, append(revoked, answersRevoked...)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, deleteQuestionVotesSQL, id); err != nil {
+			return fmt.Errorf("failed to delete votes: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deleteAnswerRevsSQL, id); err != nil {
+			return fmt.Errorf("failed to delete answer revisions: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deleteRevisionsSQL, entities.PostKindQuestion, id); err != nil {
+			return fmt.Errorf("failed to delete revisions: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deleteAnswerComsSQL, id); err != nil {
+			return fmt.Errorf("failed to delete answer comments: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deletePostCommentSQL, entities.PostKindQuestion, id); err != nil {
+			return fmt.Errorf("failed to delete comments: %w", err)
+		}
+		_, err = tx.Exec(ctx, deleteQuestionSQL, id)
 		return err
 	}); err != nil {
 		return fmt.Errorf("delete question by id: %w", err)
@@ -147,24 +353,74 @@ func (q *QuestionDB) Delete(ctx context.Context, id int64) error {
 func (q *QuestionDB) buildQuery(
 	query string,
 	filter *webutils.Filter,
+	keys *keyset,
 ) (string, []interface{}) {
 
 	conditions := make([]string, 0)
 	args := make([]interface{}, 0)
 	counter := util.NewPlaceholder()
 
-	if filter.Term != "" {
-		filterColumns := []string{"title", "body"}
-		likeStatements := make([]string, 0)
+	search := webutils.ParseSearch(filter.Term)
 
-		args = append(args, strings.ToLower(filter.Term))
-		termPlaceholder := counter.Touch()
-		for _, col := range filterColumns {
-			stmt := fmt.Sprintf("lower(%s) LIKE '%%' || $%d || '%%'", col, termPlaceholder)
-			likeStatements = append(likeStatements, stmt)
+	if search.HasText() {
+		tsquery, tsargs := tsQuery(search, counter.Touch)
+		conditions = append(conditions, " "+tsMatchSQL(tsquery))
+		args = append(args, tsargs...)
+	}
+
+	taggedCondition := func(tags []string, matchAll bool) string {
+		tagged := fmt.Sprintf(
+			"select count(distinct t.name) from question_tags qt join tags t on t.id = qt.tag_id where qt.question_id = questions.id and t.name = any($%d)",
+			counter.Touch(),
+		)
+		args = append(args, tags)
+
+		if matchAll {
+			args = append(args, len(tags))
+			return fmt.Sprintf(" (%s) = $%d", tagged, counter.Touch())
 		}
-		condition := fmt.Sprintf(" (%s)", strings.Join(likeStatements, " OR "))
+		return fmt.Sprintf(" (%s) > 0", tagged)
+	}
+
+	if len(filter.Tags) > 0 {
+		conditions = append(conditions, taggedCondition(filter.Tags, filter.MatchAllTags))
+	}
+
+	if len(search.Tags) > 0 {
+		conditions = append(conditions, taggedCondition(search.Tags, true))
+	}
+
+	if search.UserID.Valid {
+		conditions = append(conditions, fmt.Sprintf(" user_id = $%d", counter.Touch()))
+		args = append(args, search.UserID.Int64)
+	}
+
+	if search.Answered.Valid {
+		answered := " exists (select 1 from answers a where a.question_id = questions.id)"
+		if !search.Answered.Bool {
+			answered = " not" + answered
+		}
+		conditions = append(conditions, answered)
+	}
+
+	if search.Accepted.Valid {
+		conditions = append(conditions, nullCondition("accepted_answer_id", search.Accepted.Bool))
+	}
+
+	if search.Closed.Valid {
+		conditions = append(conditions, nullCondition("closed_at", search.Closed.Bool))
+	}
+
+	if search.Score != nil {
+		// Op is one of the comparisons ParseSearch accepts.
+		conditions = append(conditions, fmt.Sprintf(" %s %s $%d", questionScoreSQL, search.Score.Op, counter.Touch()))
+		args = append(args, search.Score.Value)
+	}
+
+	if keys != nil {
+		condition, keyArgs := keys.condition(counter.Touch)
 		conditions = append(conditions, condition)
+		args = append(args, keyArgs...)
 	}
 
 	if filter.FromTime.Valid && filter.ToTime.Valid {
@@ -184,18 +440,57 @@ func (q *QuestionDB) buildQuery(
 	return query, args
 }
 
-func (*QuestionDB) scan(row pgx.Row) (*entities.Question, error) {
+// nullCondition checks whether column is set.
+func nullCondition(column string, set bool) string {
+	if set {
+		return fmt.Sprintf(" %s is not null", column)
+	}
+	return fmt.Sprintf(" %s is null", column)
+}
+
+// AddViews adds the given number of views to each question in one
+// statement. Questions that no longer exist are skipped.
+func (q *QuestionDB) AddViews(ctx context.Context, views map[int64]int64) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(views))
+	counts := make([]int64, 0, len(views))
+	for id, count := range views {
+		ids = append(ids, id)
+		counts = append(counts, count)
+	}
+
+	if err := q.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, addQuestionViewsSQL, ids, counts)
+		return err
+	}); err != nil {
+		return fmt.Errorf("add question views: %w", err)
+	}
+	return nil
+}
+
+// scan reads a question, followed by any extra columns into extra.
+func (*QuestionDB) scan(row pgx.Row, extra ...interface{}) (*entities.Question, error) {
 	question := entities.NewQuestion()
 
-	if err := row.Scan(
+	if err := row.Scan(append([]interface{}{
 		&question.ID, &question.UserID, &question.Title, &question.Body, &question.Tags,
-		&question.Timestamps.CreatedAt, &question.Timestamps.UpdatedAt,
-	); err != nil {
+		&question.AcceptedAnswerID, &question.ClosedAt, &question.CloseReason, &question.DuplicateOfID,
+		&question.ViewCount, &question.Timestamps.CreatedAt, &question.Timestamps.UpdatedAt,
+	}, extra...)...); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
 
+	question.Resolved = question.AcceptedAnswerID.Valid
+	question.Closed = question.ClosedAt.Valid
+	if question.DuplicateOfID.Valid {
+		question.DuplicateOfURL = null.StringFrom(entities.QuestionURL(question.DuplicateOfID.Int64))
+	}
+
 	return question, nil
 }
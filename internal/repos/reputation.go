@@ -0,0 +1,321 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/webutils"
+
+	pgx "github.com/jackc/pgx/v4"
+	null "gopkg.in/guregu/null.v4"
+)
+
+const (
+	insertReputationSQL = `insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id) values ($1, $2, $3, $4, $5, $6)`
+	revokeReputationSQL = `insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id, revocation)
+		select user_id, -sum(amount), reason, kind, kind_id, actor_id, true from reputation_events
+		where kind = $1 and kind_id = $2 and actor_id = $3 and reason = any($4)
+		group by user_id, reason, kind, kind_id, actor_id having sum(amount) <> 0
+		returning user_id`
+	revokePostReputationSQL = `insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id, revocation)
+		select user_id, -sum(amount), reason, kind, kind_id, actor_id, true from reputation_events
+		where kind = $1 and kind_id = $2
+		group by user_id, reason, kind, kind_id, actor_id having sum(amount) <> 0
+		returning user_id`
+	revokeAnswersReputationSQL = `insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id, revocation)
+		select user_id, -sum(amount), reason, kind, kind_id, actor_id, true from reputation_events
+		where kind = 'answer' and kind_id in (select id from answers where question_id = $1)
+		group by user_id, reason, kind, kind_id, actor_id having sum(amount) <> 0
+		returning user_id`
+	refreshReputationSQL = `update users set reputation = greatest($2, $2 + coalesce(
+		(select sum(amount) from reputation_events where user_id = users.id), 0)) where id = any($1)`
+	recomputeReputationSQL = `with totals as (
+			select u.id, greatest($1, $1 + coalesce(sum(e.amount), 0)) as total
+			from users u left join reputation_events e on e.user_id = u.id group by u.id
+		)
+		update users set reputation = totals.total from totals
+		where users.id = totals.id and users.reputation <> totals.total`
+	reputationColumnsSQL  = `id, user_id, amount, reason, kind, kind_id, actor_id, revocation, created_at`
+	countReputationSQL    = `select count(id) from reputation_events where user_id = $1`
+	selectQuestionUserSQL = `select user_id from questions where id = $1`
+	selectAnswerUserSQL   = `select user_id from answers where id = $1`
+)
+
+type ReputationDB struct {
+	db *database.DB
+}
+
+func NewReputationDB(db *database.DB) *ReputationDB {
+	return &ReputationDB{
+		db: db,
+	}
+}
+
+// reputationOrder lists reputation history newest first.
+var reputationOrder = orderBy(sortKey{name: "newest", expr: "id", cast: "bigint", desc: true})
+
+// ByUser returns a page of the user's reputation history, newest first. In
+// cursor mode it also returns the cursors around the page.
+func (r *ReputationDB) ByUser(
+	ctx context.Context,
+	userID int64,
+	filter *webutils.Filter,
+) ([]*entities.ReputationEvent, *webutils.CursorPage, error) {
+	events := make([]*entities.ReputationEvent, 0)
+	values := make([][]string, 0)
+
+	scanOrder, keys, err := newKeyset("reputation", reputationOrder, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list reputation events: %w", err)
+	}
+
+	columns := reputationColumnsSQL
+	if filter.CursorMode {
+		columns += scanOrder.columns()
+	}
+
+	counter := util.NewPlaceholder()
+	query := fmt.Sprintf("select %s from reputation_events where user_id = $%d", columns, counter.Touch())
+	args := []interface{}{userID}
+
+	if keys != nil {
+		condition, keyArgs := keys.condition(counter.Touch)
+		query += " and" + condition
+		args = append(args, keyArgs...)
+	}
+	query += " order by " + scanOrder.clause()
+
+	limit, args := limitClause(filter, args)
+	query += limit
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list reputation events: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			keyValues := make([]string, len(scanOrder))
+			extra := make([]interface{}, 0)
+			if filter.CursorMode {
+				for i := range keyValues {
+					extra = append(extra, &keyValues[i])
+				}
+			}
+
+			event, err := r.scan(rows, extra...)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			events = append(events, event)
+			values = append(values, keyValues)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, nil, fmt.Errorf("list reputation events: %w", err)
+	}
+
+	if !filter.CursorMode {
+		return events, nil, nil
+	}
+
+	events, page := cursorPage("reputation", reputationOrder, filter, events, values)
+	return events, page, nil
+}
+
+func (r *ReputationDB) CountByUser(ctx context.Context, userID int64) (*int, error) {
+	var count int
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countReputationSQL, userID).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count reputation events: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count reputation events: %w", err)
+	}
+	return &count, nil
+}
+
+// Recompute rebuilds every user's reputation total from the ledger and
+// returns how many totals changed.
+func (r *ReputationDB) Recompute(ctx context.Context) (int64, error) {
+	var updated int64
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, recomputeReputationSQL, entities.MinReputation)
+		if err != nil {
+			return err
+		}
+		updated = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("recompute reputation: %w", err)
+	}
+	return updated, nil
+}
+
+// scan reads a reputation event, followed by any extra columns into extra.
+func (*ReputationDB) scan(row pgx.Row, extra ...interface{}) (*entities.ReputationEvent, error) {
+	event := entities.NewReputationEvent()
+
+	if err := row.Scan(append([]interface{}{
+		&event.ID, &event.UserID, &event.Amount, &event.Reason, &event.Kind, &event.KindID,
+		&event.ActorID, &event.Revocation, &event.CreatedAt,
+	}, extra...)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// postOwner returns the author of a question or answer, invalid if the post
+// is gone or has no author.
+func postOwner(ctx context.Context, tx pgx.Tx, kind string, kindID int64) (null.Int, error) {
+	var ownerID null.Int
+
+	query := selectQuestionUserSQL
+	if kind == entities.PostKindAnswer {
+		query = selectAnswerUserSQL
+	}
+
+	err := tx.QueryRow(ctx, query, kindID).Scan(&ownerID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return ownerID, fmt.Errorf("failed to get %v owner: %w", kind, err)
+	}
+	return ownerID, nil
+}
+
+// awardReputation records awards for actorID acting on a post written by
+// ownerID. Acting on your own post earns nothing.
+func awardReputation(
+	ctx context.Context,
+	tx pgx.Tx,
+	kind string,
+	kindID int64,
+	ownerID null.Int,
+	actorID int64,
+	awards []entities.ReputationAward,
+) ([]int64, error) {
+	if !ownerID.Valid || ownerID.Int64 == actorID {
+		return nil, nil
+	}
+
+	userIDs := make([]int64, 0, len(awards))
+	for _, award := range awards {
+		userID := ownerID.Int64
+		if award.ToActor {
+			userID = actorID
+		}
+
+		_, err := tx.Exec(ctx, insertReputationSQL, userID, award.Amount, award.Reason, kind, kindID, actorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to award reputation: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// revokeReputation takes back whatever is still outstanding from awards
+// earlier given out for actorID acting on the post.
+func revokeReputation(
+	ctx context.Context,
+	tx pgx.Tx,
+	kind string,
+	kindID int64,
+	actorID int64,
+	awards []entities.ReputationAward,
+) ([]int64, error) {
+	reasons := make([]string, 0, len(awards))
+	for _, award := range awards {
+		reasons = append(reasons, award.Reason)
+	}
+
+	return collectUserIDs(tx.Query(ctx, revokeReputationSQL, kind, kindID, actorID, reasons))
+}
+
+// revokePostReputation takes back everything earned through a post that is
+// being deleted.
+func revokePostReputation(ctx context.Context, tx pgx.Tx, kind string, kindID int64) ([]int64, error) {
+	return collectUserIDs(tx.Query(ctx, revokePostReputationSQL, kind, kindID))
+}
+
+func refreshReputation(ctx context.Context, tx pgx.Tx, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, refreshReputationSQL, userIDs, entities.MinReputation); err != nil {
+		return fmt.Errorf("failed to refresh reputation: %w", err)
+	}
+	return nil
+}
+
+// updateVoteReputation moves reputation from a vote's previous mode to its
+// new one, an empty mode meaning no vote.
+func updateVoteReputation(
+	ctx context.Context,
+	tx pgx.Tx,
+	kind string,
+	kindID int64,
+	voterID int64,
+	previousMode string,
+	mode string,
+) error {
+	userIDs := make([]int64, 0)
+
+	if previousMode != "" {
+		revoked, err := revokeReputation(ctx, tx, kind, kindID, voterID, entities.VoteAwards(kind, previousMode))
+		if err != nil {
+			return err
+		}
+		userIDs = append(userIDs, revoked...)
+	}
+
+	if mode != "" {
+		ownerID, err := postOwner(ctx, tx, kind, kindID)
+		if err != nil {
+			return err
+		}
+
+		awarded, err := awardReputation(ctx, tx, kind, kindID, ownerID, voterID, entities.VoteAwards(kind, mode))
+		if err != nil {
+			return err
+		}
+		userIDs = append(userIDs, awarded...)
+	}
+
+	return refreshReputation(ctx, tx, userIDs)
+}
+
+func collectUserIDs(rows pgx.Rows, err error) ([]int64, error) {
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke reputation: %w", err)
+	}
+	defer rows.Close()
+
+	userIDs := make([]int64, 0)
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to parse: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
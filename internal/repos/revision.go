@@ -0,0 +1,162 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/web/webutils"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	createRevisionSQL = `insert into revisions (kind, kind_id, revision, user_id, title, body, tags, summary, created_at)
+		select $1, $2, coalesce(max(revision), 0) + 1, $3, $4, $5, $6, $7, $8 from revisions where kind = $1 and kind_id = $2
+		returning id, revision`
+	selectRevisionsSQL  = `select id, kind, kind_id, revision, user_id, title, body, tags, summary, created_at, updated_at from revisions`
+	listRevisionsSQL    = selectRevisionsSQL + ` where kind = $1 and kind_id = $2 order by revision desc limit $3 offset $4`
+	getRevisionSQL      = selectRevisionsSQL + ` where kind = $1 and kind_id = $2 and revision = $3`
+	countRevisionsSQL   = `select count(id) from revisions where kind = $1 and kind_id = $2`
+	deleteRevisionsSQL  = `delete from revisions where kind = $1 and kind_id = $2`
+	deleteAnswerRevsSQL = `delete from revisions where kind = 'answer' and kind_id in (select id from answers where question_id = $1)`
+	lockAnswerSQL       = `select id from answers where id = $1 for update`
+)
+
+// ErrPostNotFound is returned when the post a revision belongs to is gone.
+var ErrPostNotFound = errors.New("post not found")
+
+type RevisionDB struct {
+	db *database.DB
+}
+
+func NewRevisionDB(db *database.DB) *RevisionDB {
+	return &RevisionDB{
+		db: db,
+	}
+}
+
+func (r *RevisionDB) ByPost(
+	ctx context.Context,
+	kind string,
+	kindID int64,
+	filter *webutils.Filter,
+) ([]*entities.Revision, error) {
+	revisions := make([]*entities.Revision, 0)
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, listRevisionsSQL, kind, kindID, filter.Per, (filter.Page-1)*filter.Per)
+		if err != nil {
+			return fmt.Errorf("failed to list revisions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			revision, err := r.scan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			revisions = append(revisions, revision)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (r *RevisionDB) CountByPost(ctx context.Context, kind string, kindID int64) (*int, error) {
+	var count int
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countRevisionsSQL, kind, kindID).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count revisions: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count revisions: %w", err)
+	}
+	return &count, nil
+}
+
+func (r *RevisionDB) ByRevision(
+	ctx context.Context,
+	kind string,
+	kindID int64,
+	revisionNum int,
+) (*entities.Revision, error) {
+	revision := entities.NewRevision()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, getRevisionSQL, kind, kindID, revisionNum)
+
+		var err error
+		revision, err = r.scan(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get revision: %w", err)
+	}
+
+	return revision, nil
+}
+
+// insertRevision appends m as the next revision of its post. It runs in the
+// caller's transaction so that the post and its history never diverge, and
+// locks the post first so concurrent edits are numbered one after another.
+func insertRevision(ctx context.Context, tx pgx.Tx, m *entities.Revision) error {
+	if errors := m.Validate(); len(errors) > 0 {
+		return fmt.Errorf("RevisionDB invalid: %v", strings.Join(errors, ", "))
+	}
+
+	lockSQL := lockQuestionSQL
+	if m.Kind == entities.PostKindAnswer {
+		lockSQL = lockAnswerSQL
+	}
+
+	var postID int64
+	if err := tx.QueryRow(ctx, lockSQL, m.KindID).Scan(&postID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrPostNotFound
+		}
+		return fmt.Errorf("failed to lock %v: %w", m.Kind, err)
+	}
+
+	m.Touch()
+	err := tx.QueryRow(
+		ctx, createRevisionSQL, m.Kind, m.KindID, m.UserID, m.Title, m.Body, m.Tags, m.Summary, m.CreatedAt,
+	).Scan(&m.ID, &m.Revision)
+	if err != nil {
+		return fmt.Errorf("inserting revision: %w", err)
+	}
+	return nil
+}
+
+func (*RevisionDB) scan(row pgx.Row) (*entities.Revision, error) {
+	revision := entities.NewRevision()
+
+	if err := row.Scan(
+		&revision.ID, &revision.Kind, &revision.KindID, &revision.Revision, &revision.UserID,
+		&revision.Title, &revision.Body, &revision.Tags, &revision.Summary,
+		&revision.CreatedAt, &revision.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,97 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+
+	"goquizbox/internal/web/webutils"
+)
+
+const (
+	searchConfig = "english"
+	// headlineOptions mark the matched words in search snippets.
+	headlineOptions = "StartSel=<mark>, StopSel=</mark>"
+	// titleHeadlineOptions and bodyHeadlineOptions shape the snippets of
+	// titles, which are highlighted whole, and of bodies, which are cut down.
+	titleHeadlineOptions = "HighlightAll=true"
+	bodyHeadlineOptions  = "MaxFragments=2, MaxWords=30, MinWords=10"
+)
+
+// htmlEscapes are the replacements escapeHTMLSQL makes, ampersands first so
+// the entities it adds are left alone.
+var htmlEscapes = [][2]string{
+	{"&", "&amp;"},
+	{"<", "&lt;"},
+	{">", "&gt;"},
+	{`"`, "&quot;"},
+	{"''", "&#39;"},
+}
+
+// escapeHTMLSQL escapes the HTML special characters of a text expression.
+func escapeHTMLSQL(expr string) string {
+	for _, escape := range htmlEscapes {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, escape[0], escape[1])
+	}
+	return expr
+}
+
+// headlineSQL selects a snippet of column with the words matching tsquery
+// wrapped in <mark> tags. The text is HTML escaped first, so the marks are
+// the only markup in the result and posts cannot inject their own.
+func headlineSQL(column, tsquery, options string) string {
+	return fmt.Sprintf(
+		"ts_headline('%s', %s, %s, '%s, %s')",
+		searchConfig, escapeHTMLSQL(column), tsquery, options, headlineOptions,
+	)
+}
+
+// tsMatchSQL matches the search vector against tsquery. A query made only
+// of stop words is empty and would match nothing, so it matches everything
+// instead and the rest of the search still applies.
+func tsMatchSQL(tsquery string) string {
+	return fmt.Sprintf("(search_vector @@ %[1]v or numnode(%[1]v) = 0)", tsquery)
+}
+
+// tsRankSQL ranks rows by how well they match tsquery.
+func tsRankSQL(tsquery string) sortKey {
+	return sortKey{expr: fmt.Sprintf("ts_rank(search_vector, %v)", tsquery), cast: "real", desc: true}
+}
+
+// likeEscaper escapes the wildcards of a like pattern, for matching user
+// input literally with "escape '\'".
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsSQL matches column against the placeholder as a literal
+// substring, the placeholder's value having been escaped by escapeLike.
+func containsSQL(column string, placeholder int) string {
+	return fmt.Sprintf(`%s like '%%' || $%d || '%%' escape '\'`, column, placeholder)
+}
+
+// escapeLike lowercases s and escapes it for containsSQL.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(strings.ToLower(s))
+}
+
+// tsQuery builds the full-text query matching the words, phrases and
+// exclusions of search, taking its placeholders from next.
+func tsQuery(search *webutils.SearchQuery, next func() int) (string, []interface{}) {
+	parts := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if len(search.Words) > 0 {
+		parts = append(parts, fmt.Sprintf("plainto_tsquery('%s', $%d)", searchConfig, next()))
+		args = append(args, strings.Join(search.Words, " "))
+	}
+
+	for _, phrase := range search.Phrases {
+		parts = append(parts, fmt.Sprintf("phraseto_tsquery('%s', $%d)", searchConfig, next()))
+		args = append(args, phrase)
+	}
+
+	for _, excluded := range search.Excluded {
+		parts = append(parts, fmt.Sprintf("!!phraseto_tsquery('%s', $%d)", searchConfig, next()))
+		args = append(args, excluded)
+	}
+
+	return "(" + strings.Join(parts, " && ") + ")", args
+}
@@ -0,0 +1,38 @@
+package repos
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	testCases := map[string]string{
+		"Deploy":    "deploy",
+		"100%":      `100\%`,
+		"snake_key": `snake\_key`,
+		`C:\keys`:   `c:\\keys`,
+	}
+
+	for input, want := range testCases {
+		if got := escapeLike(input); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	if got, want := containsSQL("lower(name)", 2), `lower(name) like '%' || $2 || '%' escape '\'`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeadlineSQL(t *testing.T) {
+	got := headlineSQL("body", "q", bodyHeadlineOptions)
+	want := `ts_headline('english', replace(replace(replace(replace(replace(body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'), q, 'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=<mark>, StopSel=</mark>')`
+	if got != want {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func TestTsMatchSQL(t *testing.T) {
+	got := tsMatchSQL("plainto_tsquery('english', $1)")
+	want := "(search_vector @@ plainto_tsquery('english', $1) or numnode(plainto_tsquery('english', $1)) = 0)"
+	if got != want {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
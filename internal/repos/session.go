@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"goquizbox/internal/database"
 	"goquizbox/internal/entities"
@@ -16,11 +17,17 @@ const (
 	createSessionSQL      = `insert into sessions (user_id, deactivated_at, expires_at, ip_address, last_refreshed_at, user_agent, created_at) values ($1, $2, $3, $4, $5, $6, $7) returning id`
 	selectSessionsSQL     = `select id, user_id, deactivated_at, expires_at, ip_address, last_refreshed_at, user_agent, created_at, updated_at from sessions`
 	getSessionByIDSQL     = selectSessionsSQL + " where id=$1"
-	getFullSessionByIDSQL = `select s.id, s.deactivated_at, s.ip_address, s.last_refreshed_at,
-		s.user_agent, s.user_id, u.status AS user_status, s.created_at, s.updated_at from sessions s join users u ON s.user_id = u.id where s.id = $1`
+	getFullSessionByIDSQL = `select s.id, s.deactivated_at, s.expires_at, s.ip_address, s.last_refreshed_at,
+		s.user_agent, s.user_id, u.status AS user_status, u.role AS user_role, s.created_at, s.updated_at from sessions s join users u ON s.user_id = u.id where s.id = $1`
+	deactivateOtherSessionsSQL = `update sessions set deactivated_at = $1, updated_at = $1
+		where user_id = $2 and id <> $3 and deactivated_at is null`
 	updateSessionSQL = `UPDATE sessions SET (deactivated_at, ip_address,
-		last_refreshed_at, user_agent, user_id, updated_at) =
-		($1, $2, $3, $4, $5, $6) WHERE id = $7`
+		last_refreshed_at, user_agent, user_id, updated_at, expires_at) =
+		($1, $2, $3, $4, $5, $6, $7) WHERE id = $8`
+	listActiveSessionsSQL = selectSessionsSQL + ` where user_id = $1 and deactivated_at is null
+		and (expires_at is null or expires_at > $2) order by last_refreshed_at desc`
+	deactivateSessionSQL = `update sessions set deactivated_at = $1, updated_at = $1
+		where id = $2 and user_id = $3 and deactivated_at is null`
 )
 
 type SessionDB struct {
@@ -54,7 +61,7 @@ func (u *SessionDB) Save(ctx context.Context, m *entities.Session) error {
 
 		_, err := tx.Exec(
 			ctx, updateSessionSQL, m.DeactivatedAt, m.IPAddress, m.LastRefreshedAt,
-			m.UserAgent, m.UserID, m.UpdatedAt, m.ID,
+			m.UserAgent, m.UserID, m.UpdatedAt, m.ExpiresAt, m.ID,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to update session: %w", err)
@@ -83,14 +90,15 @@ func (r *SessionDB) GetSessionByID(ctx context.Context, id int64) (*entities.Ses
 }
 
 func (r *SessionDB) GetFullSessionByID(ctx context.Context, id int64) (*entities.FullSession, error) {
-	var session entities.FullSession
+	var session *entities.FullSession
 
 	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var s entities.FullSession
 		row := tx.QueryRow(ctx, getFullSessionByIDSQL, id)
 		err := row.Scan(
-			&session.ID, &session.DeactivatedAt, &session.IPAddress, &session.LastRefreshedAt,
-			&session.UserAgent, &session.UserID, &session.UserStatus,
-			&session.Timestamps.CreatedAt, &session.Timestamps.UpdatedAt,
+			&s.ID, &s.DeactivatedAt, &s.ExpiresAt, &s.IPAddress, &s.LastRefreshedAt,
+			&s.UserAgent, &s.UserID, &s.UserStatus, &s.UserRole,
+			&s.Timestamps.CreatedAt, &s.Timestamps.UpdatedAt,
 		)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
@@ -98,12 +106,79 @@ func (r *SessionDB) GetFullSessionByID(ctx context.Context, id int64) (*entities
 			}
 			return err
 		}
+		session = &s
 		return nil
 	}); err != nil {
 		return nil, fmt.Errorf("get full session by id: %w", err)
 	}
 
-	return &session, nil
+	return session, nil
+}
+
+// ActiveByUser returns the user's sessions that are neither deactivated nor
+// expired, most recently used first.
+func (r *SessionDB) ActiveByUser(ctx context.Context, userID int64) ([]*entities.Session, error) {
+	sessions := make([]*entities.Session, 0)
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, listActiveSessionsSQL, userID, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			session, err := r.scan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			sessions = append(sessions, session)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("list active sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Deactivate ends one of the user's sessions. It reports false if the user
+// has no such active session.
+func (r *SessionDB) Deactivate(ctx context.Context, id, userID int64) (bool, error) {
+	var deactivated bool
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, deactivateSessionSQL, time.Now(), id, userID)
+		if err != nil {
+			return err
+		}
+		deactivated = result.RowsAffected() > 0
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("deactivate session: %w", err)
+	}
+	return deactivated, nil
+}
+
+// DeactivateOthers ends every session of the user except keepID and returns
+// how many were ended.
+func (r *SessionDB) DeactivateOthers(ctx context.Context, userID, keepID int64) (int64, error) {
+	var deactivated int64
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, deactivateOtherSessionsSQL, time.Now(), userID, keepID)
+		if err != nil {
+			return err
+		}
+		deactivated = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("deactivate other sessions: %w", err)
+	}
+	return deactivated, nil
 }
 
 func (r *SessionDB) scan(row pgx.Row) (*entities.Session, error) {
@@ -0,0 +1,237 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"goquizbox/internal/web/webutils"
+)
+
+// ErrUnknownSort is returned when a listing is asked to order by a field it
+// does not support.
+var ErrUnknownSort = errors.New("unknown sort")
+
+type (
+	// sortKey is an SQL expression a listing can be ordered by. Cursors
+	// carry its value as text, which is cast back to cast when compared.
+	sortKey struct {
+		name string
+		expr string
+		cast string
+		desc bool
+	}
+
+	sortKeys map[string]sortKey
+
+	// ordering is every key a listing is ordered by, ending with id so no
+	// two rows tie.
+	ordering []sortKey
+
+	// keyset limits a listing to the rows after values in ordering.
+	keyset struct {
+		ordering ordering
+		values   []string
+	}
+)
+
+var idSortKey = sortKey{name: "id", expr: "id", cast: "bigint"}
+
+// with returns a copy of the keys with key added as name.
+func (s sortKeys) with(name string, key sortKey) sortKeys {
+	keys := make(sortKeys, len(s)+1)
+	for k, v := range s {
+		keys[k] = v
+	}
+	keys[name] = key
+	return keys
+}
+
+// resolve picks the sort key named by order, or def when it names none.
+func (s sortKeys) resolve(order *webutils.OrderFilter, def string) (sortKey, error) {
+	name := def
+	if order != nil && order.Field != "" {
+		name = order.Field
+	}
+
+	key, ok := s[name]
+	if !ok {
+		return key, fmt.Errorf("%w %q", ErrUnknownSort, name)
+	}
+	key.name = name
+
+	if order != nil {
+		switch order.Order {
+		case webutils.OrderAsc:
+			key.desc = false
+		case webutils.OrderDesc:
+			key.desc = true
+		}
+	}
+
+	return key, nil
+}
+
+func (k sortKey) direction() string {
+	if k.desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// orderBy orders by keys, then by id in the direction of the last key.
+func orderBy(keys ...sortKey) ordering {
+	last := keys[len(keys)-1]
+	if last.expr == idSortKey.expr {
+		return keys
+	}
+
+	id := idSortKey
+	id.desc = last.desc
+	return append(keys, id)
+}
+
+// sortID names the ordering of listing, so a cursor cannot be used with
+// another one.
+func (o ordering) sortID(listing string) string {
+	parts := []string{listing}
+	for _, key := range o {
+		parts = append(parts, key.name+":"+key.direction())
+	}
+	return strings.Join(parts, ",")
+}
+
+// clause is the order by list, without the "order by".
+func (o ordering) clause() string {
+	terms := make([]string, 0, len(o))
+	for _, key := range o {
+		terms = append(terms, key.expr+" "+key.direction())
+	}
+	return strings.Join(terms, ", ")
+}
+
+func (o ordering) reverse() ordering {
+	reversed := make(ordering, 0, len(o))
+	for _, key := range o {
+		key.desc = !key.desc
+		reversed = append(reversed, key)
+	}
+	return reversed
+}
+
+// columns selects the keys as text, to be read into cursors.
+func (o ordering) columns() string {
+	columns := ""
+	for _, key := range o {
+		columns += fmt.Sprintf(", (%s)::text", key.expr)
+	}
+	return columns
+}
+
+func (k *keyset) condition(next func() int) (string, []interface{}) {
+	values := make([]string, 0, len(k.ordering))
+	args := make([]interface{}, 0, len(k.ordering))
+	for i, key := range k.ordering {
+		values = append(values, fmt.Sprintf("cast($%d as %s)", next(), key.cast))
+		args = append(args, k.values[i])
+	}
+
+	sameDirection := true
+	for _, key := range k.ordering {
+		sameDirection = sameDirection && key.desc == k.ordering[0].desc
+	}
+
+	// A row comparison can use an index, but only works when every key
+	// runs the same way.
+	if sameDirection {
+		exprs := make([]string, 0, len(k.ordering))
+		for _, key := range k.ordering {
+			exprs = append(exprs, key.expr)
+		}
+		return fmt.Sprintf(
+			" (%s) %s (%s)",
+			strings.Join(exprs, ", "), k.ordering[0].comparison(), strings.Join(values, ", "),
+		), args
+	}
+
+	alternatives := make([]string, 0, len(k.ordering))
+	for i, key := range k.ordering {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = %s", k.ordering[j].expr, values[j]))
+		}
+		terms = append(terms, fmt.Sprintf("%s %s %s", key.expr, key.comparison(), values[i]))
+		alternatives = append(alternatives, "("+strings.Join(terms, " and ")+")")
+	}
+	return " (" + strings.Join(alternatives, " or ") + ")", args
+}
+
+// comparison is the operator matching rows that come after a value.
+func (k sortKey) comparison() string {
+	if k.desc {
+		return "<"
+	}
+	return ">"
+}
+
+// newKeyset checks that the filter's cursor was issued for ord and returns
+// the ordering to read rows in along with the keyset to start from.
+func newKeyset(listing string, ord ordering, filter *webutils.Filter) (ordering, *keyset, error) {
+	if filter.Cursor == nil {
+		return ord, nil, nil
+	}
+
+	if filter.Cursor.Sort != ord.sortID(listing) || len(filter.Cursor.Values) != len(ord) {
+		return nil, nil, webutils.ErrInvalidCursor
+	}
+
+	if filter.Cursor.Before {
+		ord = ord.reverse()
+	}
+	return ord, &keyset{ordering: ord, values: filter.Cursor.Values}, nil
+}
+
+// limitClause pages by offset, or fetches one row more than a page in
+// cursor mode to tell whether another page follows.
+func limitClause(filter *webutils.Filter, args []interface{}) (string, []interface{}) {
+	if filter.CursorMode {
+		return fmt.Sprintf(" limit $%d", len(args)+1), append(args, filter.Per+1)
+	}
+
+	if filter.Per > 0 && filter.Page > 0 {
+		return fmt.Sprintf(" limit $%d offset $%d", len(args)+1, len(args)+2), append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+	return "", args
+}
+
+// cursorPage trims rows read in cursor mode down to a page, puts them back
+// in listing order and works out the cursors around them. values holds the
+// sort key values of each row.
+func cursorPage[T any](listing string, ord ordering, filter *webutils.Filter, rows []T, values [][]string) ([]T, *webutils.CursorPage) {
+	backward := filter.Cursor != nil && filter.Cursor.Before
+	more := len(rows) > filter.Per
+	if more {
+		rows, values = rows[:filter.Per], values[:filter.Per]
+	}
+
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	page := &webutils.CursorPage{}
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	sort := ord.sortID(listing)
+	if backward || more {
+		page.Next = &webutils.Cursor{Sort: sort, Values: values[len(values)-1]}
+	}
+	if (backward && more) || (!backward && filter.Cursor != nil) {
+		page.Prev = &webutils.Cursor{Sort: sort, Values: values[0], Before: true}
+	}
+	return rows, page
+}
@@ -0,0 +1,85 @@
+package repos
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/webutils"
+)
+
+func TestSortKeysResolve(t *testing.T) {
+	key, err := questionSorts.resolve(nil, "newest")
+	if err != nil || key.name != "newest" || !key.desc {
+		t.Fatalf("got %+v, %v", key, err)
+	}
+
+	key, err = questionSorts.resolve(&webutils.OrderFilter{Field: "votes", Order: webutils.OrderAsc}, "newest")
+	if err != nil || key.name != "votes" || key.desc {
+		t.Fatalf("got %+v, %v", key, err)
+	}
+
+	if _, err := questionSorts.resolve(&webutils.OrderFilter{Field: "password_hash"}, "newest"); !errors.Is(err, ErrUnknownSort) {
+		t.Errorf("expected ErrUnknownSort, got %v", err)
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	created := sortKey{name: "newest", expr: "created_at", cast: "timestamptz", desc: true}
+
+	keys := &keyset{ordering: orderBy(created), values: []string{"2023-01-01", "7"}}
+	condition, args := keys.condition(util.NewPlaceholder().Touch)
+	if want := " (created_at, id) < (cast($1 as timestamptz), cast($2 as bigint))"; condition != want {
+		t.Errorf("got %q, want %q", condition, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"2023-01-01", "7"}) {
+		t.Errorf("got args %v", args)
+	}
+
+	created.desc = false
+	keys = &keyset{ordering: orderBy(acceptedSortKey, created), values: []string{"true", "2023-01-01", "7"}}
+	condition, _ = keys.condition(util.NewPlaceholder().Touch)
+	want := " ((" + answerAcceptedSQL + " < cast($1 as boolean)) or (" +
+		answerAcceptedSQL + " = cast($1 as boolean) and created_at > cast($2 as timestamptz)) or (" +
+		answerAcceptedSQL + " = cast($1 as boolean) and created_at = cast($2 as timestamptz) and id > cast($3 as bigint)))"
+	if condition != want {
+		t.Errorf("got %q, want %q", condition, want)
+	}
+}
+
+func TestCursorPage(t *testing.T) {
+	ord := orderBy(sortKey{name: "newest", expr: "created_at", cast: "timestamptz", desc: true})
+	sort := ord.sortID("questions")
+	values := [][]string{{"c", "3"}, {"b", "2"}, {"a", "1"}}
+
+	rows, page := cursorPage("questions", ord, &webutils.Filter{Per: 2, CursorMode: true}, []int{3, 2, 1}, values)
+	if !reflect.DeepEqual(rows, []int{3, 2}) {
+		t.Errorf("got rows %v", rows)
+	}
+	if page.Prev != nil || !reflect.DeepEqual(page.Next, &webutils.Cursor{Sort: sort, Values: []string{"b", "2"}}) {
+		t.Errorf("got first page %+v %+v", page.Prev, page.Next)
+	}
+
+	// Reading backwards, rows come in reverse and the extra row means there
+	// are more before them.
+	before := &webutils.Cursor{Sort: sort, Values: []string{"z", "9"}, Before: true}
+	rows, page = cursorPage("questions", ord, &webutils.Filter{Per: 2, CursorMode: true, Cursor: before},
+		[]int{1, 2, 3}, [][]string{{"a", "1"}, {"b", "2"}, {"c", "3"}})
+	if !reflect.DeepEqual(rows, []int{2, 1}) {
+		t.Errorf("got rows %v", rows)
+	}
+	if !reflect.DeepEqual(page.Prev, &webutils.Cursor{Sort: sort, Values: []string{"b", "2"}, Before: true}) ||
+		!reflect.DeepEqual(page.Next, &webutils.Cursor{Sort: sort, Values: []string{"a", "1"}}) {
+		t.Errorf("got backward page %+v %+v", page.Prev, page.Next)
+	}
+}
+
+func TestNewKeysetRejectsForeignCursor(t *testing.T) {
+	ord := orderBy(sortKey{name: "newest", expr: "created_at", cast: "timestamptz", desc: true})
+	cursor := &webutils.Cursor{Sort: ord.sortID("users"), Values: []string{"a", "1"}}
+
+	if _, _, err := newKeyset("questions", ord, &webutils.Filter{Cursor: cursor}); !errors.Is(err, webutils.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
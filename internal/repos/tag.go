@@ -0,0 +1,142 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+	"goquizbox/internal/util"
+	"goquizbox/internal/web/webutils"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	selectTagsSQL = `select t.id, t.name, count(qt.question_id), t.created_at, t.updated_at
+		from tags t left join question_tags qt on qt.tag_id = t.id`
+	countTagsSQL         = `select count(t.id) from tags t`
+	createTagsSQL        = `insert into tags (name) select unnest($1::text[]) on conflict (name) do nothing`
+	clearQuestionTagsSQL = `delete from question_tags where question_id = $1`
+	addQuestionTagsSQL   = `insert into question_tags (question_id, tag_id) select $1, id from tags where name = any($2)`
+)
+
+type TagDB struct {
+	db *database.DB
+}
+
+func NewTagDB(db *database.DB) *TagDB {
+	return &TagDB{
+		db: db,
+	}
+}
+
+// List returns tags along with the number of questions using each, most used
+// first.
+func (r *TagDB) List(ctx context.Context, filter *webutils.Filter) ([]*entities.Tag, error) {
+	tags := make([]*entities.Tag, 0)
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		query, args := r.buildQuery(selectTagsSQL, filter)
+		query += " group by t.id order by count(qt.question_id) desc, t.name"
+
+		if filter.Per > 0 && filter.Page > 0 {
+			query += fmt.Sprintf(" limit $%d offset $%d", len(args)+1, len(args)+2)
+			args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+		}
+
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list tags: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			tag, err := r.scan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			tags = append(tags, tag)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (r *TagDB) Count(ctx context.Context, filter *webutils.Filter) (*int, error) {
+	var count int
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		query, args := r.buildQuery(countTagsSQL, filter)
+		err := tx.QueryRow(ctx, query, args...).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count tags: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count tags: %w", err)
+	}
+	return &count, nil
+}
+
+// saveQuestionTags replaces the tags on a question, creating any tag that
+// does not exist yet. It runs in the caller's transaction.
+func saveQuestionTags(ctx context.Context, tx pgx.Tx, questionID int64, tags []string) error {
+	if _, err := tx.Exec(ctx, createTagsSQL, tags); err != nil {
+		return fmt.Errorf("failed to create tags: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, clearQuestionTagsSQL, questionID); err != nil {
+		return fmt.Errorf("failed to clear question tags: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, addQuestionTagsSQL, questionID, tags); err != nil {
+		return fmt.Errorf("failed to add question tags: %w", err)
+	}
+	return nil
+}
+
+func (r *TagDB) buildQuery(
+	query string,
+	filter *webutils.Filter,
+) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	counter := util.NewPlaceholder()
+
+	if filter.Term != "" {
+		conditions = append(conditions, " "+containsSQL("t.name", counter.Touch()))
+		args = append(args, escapeLike(filter.Term))
+	}
+
+	if len(conditions) > 0 {
+		query += " where" + strings.Join(conditions, " and ")
+	}
+
+	return query, args
+}
+
+func (*TagDB) scan(row pgx.Row) (*entities.Tag, error) {
+	tag := entities.NewTag()
+
+	if err := row.Scan(
+		&tag.ID, &tag.Name, &tag.QuestionCount, &tag.CreatedAt, &tag.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,274 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goquizbox/internal/database"
+	"goquizbox/internal/entities"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+const (
+	setPendingTOTPSQL       = `update users set totp_secret = $1, totp_last_step = null, updated_at = $2 where id = $3 and totp_enabled_at is null`
+	enableTOTPSQL           = `update users set totp_enabled_at = $1, totp_last_step = $2, updated_at = $1 where id = $3 and totp_secret is not null and totp_enabled_at is null`
+	disableTOTPSQL          = `update users set totp_secret = null, totp_enabled_at = null, totp_last_step = null, updated_at = $1 where id = $2`
+	useTOTPStepSQL          = `update users set totp_last_step = $1 where id = $2 and totp_enabled_at is not null and (totp_last_step is null or totp_last_step < $1)`
+	deleteRecoveryCodesSQL  = `delete from recovery_codes where user_id = $1`
+	createRecoveryCodeSQL   = `insert into recovery_codes (user_id, code_hash, created_at) values ($1, $2, $3)`
+	useRecoveryCodeSQL      = `update recovery_codes set used_at = $1 where user_id = $2 and code_hash = $3 and used_at is null`
+	countRecoveryCodesSQL   = `select count(id) from recovery_codes where user_id = $1 and used_at is null`
+	createLoginChallengeSQL = `insert into login_challenges (user_id, token_hash, expires_at, created_at) values ($1, $2, $3, $4) returning id`
+	getLoginChallengeSQL    = `select id, user_id, token_hash, attempts, expires_at, used_at, created_at from login_challenges where token_hash = $1`
+	useLoginChallengeSQL    = `update login_challenges set used_at = $1 where id = $2 and used_at is null and attempts <= $3 and expires_at > $1`
+	claimLoginChallengeSQL  = `update login_challenges set attempts = attempts + 1
+		where id = $1 and used_at is null and attempts < $2 and expires_at > $3 returning attempts`
+)
+
+var (
+	ErrTwoFactorState         = errors.New("two-factor authentication is not in the expected state")
+	ErrLoginChallengeUsed     = errors.New("login challenge already used or expired")
+	errRecoveryCodesNotStored = errors.New("recovery codes were not stored")
+)
+
+type TwoFactorDB struct {
+	db *database.DB
+}
+
+func NewTwoFactorDB(db *database.DB) *TwoFactorDB {
+	return &TwoFactorDB{
+		db: db,
+	}
+}
+
+// SetPendingSecret stores a secret for an enrollment that still has to be
+// confirmed. It fails with ErrTwoFactorState if 2FA is already enabled.
+func (r *TwoFactorDB) SetPendingSecret(ctx context.Context, userID int64, secret string) error {
+	err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, setPendingTOTPSQL, secret, time.Now(), userID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrTwoFactorState
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorState) {
+			return err
+		}
+		return fmt.Errorf("set pending totp secret: %w", err)
+	}
+	return nil
+}
+
+// Enable confirms a pending enrollment, marking step as used and replacing
+// the user's recovery codes with codeHashes.
+func (r *TwoFactorDB) Enable(ctx context.Context, userID, step int64, codeHashes []string) error {
+	now := time.Now()
+	err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, enableTOTPSQL, now, step, userID)
+		if err != nil {
+			return fmt.Errorf("failed to enable totp: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrTwoFactorState
+		}
+
+		return r.replaceRecoveryCodes(ctx, tx, userID, codeHashes, now)
+	})
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorState) {
+			return err
+		}
+		return fmt.Errorf("enable totp: %w", err)
+	}
+	return nil
+}
+
+// Disable removes the user's secret and recovery codes.
+func (r *TwoFactorDB) Disable(ctx context.Context, userID int64) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, disableTOTPSQL, time.Now(), userID); err != nil {
+			return fmt.Errorf("failed to disable totp: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deleteRecoveryCodesSQL, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("disable totp: %w", err)
+	}
+	return nil
+}
+
+// UseStep records step as the last one accepted for the user. It returns
+// false if that step, or a later one, was already used.
+func (r *TwoFactorDB) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	var used bool
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, useTOTPStepSQL, step, userID)
+		if err != nil {
+			return err
+		}
+		used = result.RowsAffected() == 1
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("use totp step: %w", err)
+	}
+	return used, nil
+}
+
+// UseRecoveryCode spends the recovery code with codeHash. It returns false
+// if the user has no such unused code.
+func (r *TwoFactorDB) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	var used bool
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, useRecoveryCodeSQL, time.Now(), userID, codeHash)
+		if err != nil {
+			return err
+		}
+		used = result.RowsAffected() == 1
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	return used, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user who has
+// 2FA enabled.
+func (r *TwoFactorDB) RegenerateRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		return r.replaceRecoveryCodes(ctx, tx, userID, codeHashes, time.Now())
+	}); err != nil {
+		return fmt.Errorf("regenerate recovery codes: %w", err)
+	}
+	return nil
+}
+
+func (r *TwoFactorDB) CountRecoveryCodes(ctx context.Context, userID int64) (*int, error) {
+	var count int
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, countRecoveryCodesSQL, userID).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("count recovery codes: %w", err)
+	}
+	return &count, nil
+}
+
+func (r *TwoFactorDB) replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string, now time.Time) error {
+	if len(codeHashes) == 0 {
+		return errRecoveryCodesNotStored
+	}
+
+	if _, err := tx.Exec(ctx, deleteRecoveryCodesSQL, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec(ctx, createRecoveryCodeSQL, userID, codeHash, now); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *TwoFactorDB) CreateChallenge(ctx context.Context, m *entities.LoginChallenge) error {
+	m.CreatedAt = time.Now()
+	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		err := tx.QueryRow(
+			ctx, createLoginChallengeSQL, m.UserID, m.TokenHash, m.ExpiresAt, m.CreatedAt,
+		).Scan(&m.ID)
+		if err != nil {
+			return fmt.Errorf("inserting login challenge: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *TwoFactorDB) ChallengeByTokenHash(ctx context.Context, tokenHash string) (*entities.LoginChallenge, error) {
+	challenge := entities.NewLoginChallenge()
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, getLoginChallengeSQL, tokenHash)
+
+		var err error
+		challenge, err = r.scanChallenge(row)
+		if err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get login challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// ClaimChallengeAttempt uses up one of the challenge's attempts before its
+// code is checked, so parallel guesses cannot get past the limit. It fails
+// with ErrLoginChallengeUsed if no attempts are left or the challenge was
+// spent or expired.
+func (r *TwoFactorDB) ClaimChallengeAttempt(ctx context.Context, m *entities.LoginChallenge) error {
+	err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		return tx.QueryRow(
+			ctx, claimLoginChallengeSQL, m.ID, entities.MaxLoginChallengeAttempts, time.Now(),
+		).Scan(&m.Attempts)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrLoginChallengeUsed
+		}
+		return fmt.Errorf("claim login challenge attempt: %w", err)
+	}
+	return nil
+}
+
+// UseChallenge spends the challenge. It fails with ErrLoginChallengeUsed
+// if it was spent, exhausted or expired in the meantime.
+func (r *TwoFactorDB) UseChallenge(ctx context.Context, m *entities.LoginChallenge) error {
+	now := time.Now()
+	err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, useLoginChallengeSQL, now, m.ID, entities.MaxLoginChallengeAttempts)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrLoginChallengeUsed
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrLoginChallengeUsed) {
+			return err
+		}
+		return fmt.Errorf("use login challenge: %w", err)
+	}
+
+	m.UsedAt.SetValid(now)
+	return nil
+}
+
+func (*TwoFactorDB) scanChallenge(row pgx.Row) (*entities.LoginChallenge, error) {
+	challenge := entities.NewLoginChallenge()
+
+	if err := row.Scan(
+		&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.Attempts,
+		&challenge.ExpiresAt, &challenge.UsedAt, &challenge.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return challenge, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"goquizbox/internal/database"
 	"goquizbox/internal/entities"
@@ -15,16 +16,40 @@ import (
 )
 
 const (
-	createUserSQL     = `insert into users (first_name, last_name, email, email_verified, status, password_hash, created_at) values ($1, $2, $3, $4, $5, $6, $7) returning id`
-	updateUserSQL     = `update users set first_name=$1, last_name=$2, email=$3, email_activation_key=$4, status=$5, updated_at=$6 where id = $7`
-	getUsersSQL       = `select id, first_name, last_name, email, email_activation_key, email_verified, status, password_hash, created_at, updated_at from users`
+	createUserSQL     = `insert into users (first_name, last_name, email, email_activation_key, email_activation_at, email_verified, status, password_hash, created_at) values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`
+	updateUserSQL     = `update users set first_name=$1, last_name=$2, email=$3, email_activation_key=$4, email_verified=$5, status=$6, updated_at=$7 where id = $8`
+	userColumnsSQL    = `id, first_name, last_name, email, email_activation_key, email_activation_at, email_verified, status, role, password_hash, reputation, totp_secret, totp_enabled_at, totp_last_step, created_at, updated_at`
+	getUsersSQL       = `select ` + userColumnsSQL + ` from users`
 	getUserByIDSQL    = getUsersSQL + ` where id=$1`
 	getUserByEmailSQL = getUsersSQL + ` where lower(email)=lower($1)`
 	getUserByPhoneSQL = getUsersSQL + ` where phone=$1`
 	countUsersSQL     = "select count(id) from users"
 	deleteUserSQL     = `delete from users where id=$1`
+	setActivationSQL  = `update users set email_activation_key=$1, email_activation_at=$2, email_activation_attempts=0 where id=$3`
+	setPasswordSQL    = `update users set password_hash=$1, updated_at=$2 where id=$3`
+	setRoleSQL        = `update users set role=$1, updated_at=$2 where id=$3`
+	setStatusSQL      = `update users set status=$1, updated_at=$2 where id=$3`
+	verifyEmailSQL    = `update users set email_verified=true, email_activation_key=null, email_activation_at=null, email_activation_attempts=0, status=(case when status='unverified' then 'active' else status end), updated_at=$1 where id=$2`
+	// claimActivationSQL uses up one attempt at the activation key, so the
+	// limit holds however many guesses arrive at once.
+	claimActivationSQL = `update users set email_activation_attempts = email_activation_attempts + 1
+		where id=$1 and email_activation_key is not null and email_activation_at is not null
+			and email_activation_attempts < $2
+		returning email_activation_key, email_activation_at`
 )
 
+// ErrActivationExhausted is returned when a user has no activation key or
+// has used up their guesses at it.
+var ErrActivationExhausted = errors.New("email activation key missing or out of attempts")
+
+// userSorts are the orders users can be listed in.
+var userSorts = sortKeys{
+	"newest":     {expr: "created_at", cast: "timestamptz", desc: true},
+	"oldest":     {expr: "created_at", cast: "timestamptz"},
+	"reputation": {expr: "reputation", cast: "bigint", desc: true},
+	"name":       {expr: "lower(first_name || ' ' || last_name)", cast: "text"},
+}
+
 type UserDB struct {
 	db *database.DB
 }
@@ -35,14 +60,46 @@ func NewUserDB(db *database.DB) *UserDB {
 	}
 }
 
-func (r *UserDB) List(ctx context.Context, filter *webutils.Filter) ([]*entities.User, error) {
+// List returns a page of the users matching filter, newest first unless
+// order says otherwise. In cursor mode it also returns the cursors around
+// the page. The search term only matches emails when searchEmail is set,
+// so public listings cannot be used to look users up by email.
+func (r *UserDB) List(
+	ctx context.Context,
+	filter *webutils.Filter,
+	order *webutils.OrderFilter,
+	searchEmail bool,
+) ([]*entities.User, *webutils.CursorPage, error) {
 	users := make([]*entities.User, 0)
+	values := make([][]string, 0)
+
+	sort, err := userSorts.resolve(order, "newest")
+	if err != nil {
+		return nil, nil, fmt.Errorf("list users: %w", err)
+	}
+
+	ord := orderBy(sort)
+	scanOrder, keys, err := newKeyset("users", ord, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list users: %w", err)
+	}
+
+	query := getUsersSQL
+	if filter.CursorMode {
+		query = "select " + userColumnsSQL + scanOrder.columns() + " from users"
+	}
 
 	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		query, args := r.buildQuery(
-			getUsersSQL,
+			query,
 			filter,
+			keys,
+			searchEmail,
 		)
+		query += " order by " + scanOrder.clause()
+
+		limit, args := limitClause(filter, args)
+		query += limit
 
 		rows, err := tx.Query(ctx, query, args...)
 		if err != nil {
@@ -55,22 +112,36 @@ func (r *UserDB) List(ctx context.Context, filter *webutils.Filter) ([]*entities
 				return fmt.Errorf("failed to iterate: %w", err)
 			}
 
-			user, err := r.scan(rows)
+			keyValues := make([]string, len(scanOrder))
+			extra := make([]interface{}, 0)
+			if filter.CursorMode {
+				for i := range keyValues {
+					extra = append(extra, &keyValues[i])
+				}
+			}
+
+			user, err := r.scan(rows, extra...)
 			if err != nil {
 				return fmt.Errorf("failed to parse: %w", err)
 			}
 			users = append(users, user)
+			values = append(values, keyValues)
 		}
 
 		return nil
 	}); err != nil {
-		return nil, fmt.Errorf("list users: %w", err)
+		return nil, nil, fmt.Errorf("list users: %w", err)
 	}
 
-	return users, nil
+	if !filter.CursorMode {
+		return users, nil, nil
+	}
+
+	users, page := cursorPage("users", ord, filter, users, values)
+	return users, page, nil
 }
 
-func (r *UserDB) Count(ctx context.Context, filter *webutils.Filter) (*int, error) {
+func (r *UserDB) Count(ctx context.Context, filter *webutils.Filter, searchEmail bool) (*int, error) {
 	var count int
 	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		query, args := r.buildQuery(
@@ -78,6 +149,8 @@ func (r *UserDB) Count(ctx context.Context, filter *webutils.Filter) (*int, erro
 			&webutils.Filter{
 				Term: filter.Term,
 			},
+			nil,
+			searchEmail,
 		)
 		err := tx.QueryRow(ctx, query, args...).Scan(&count)
 		if err != nil {
@@ -100,8 +173,8 @@ func (r *UserDB) Save(ctx context.Context, m *entities.User) error {
 	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		if m.IsNew() {
 			err := tx.QueryRow(
-				ctx, createUserSQL, m.FirstName, m.LastName, m.Email,
-				m.EmailVerified, m.Status, m.PasswordHash, m.CreatedAt,
+				ctx, createUserSQL, m.FirstName, m.LastName, m.Email, m.EmailActivationKey,
+				m.EmailActivationAt, m.EmailVerified, m.Status, m.PasswordHash, m.CreatedAt,
 			).Scan(&m.ID)
 			if err != nil {
 				return fmt.Errorf("inserting user: %w", err)
@@ -110,7 +183,7 @@ func (r *UserDB) Save(ctx context.Context, m *entities.User) error {
 		}
 		_, err := tx.Exec(
 			ctx, updateUserSQL, m.FirstName, m.LastName, m.Email, m.EmailActivationKey,
-			m.Status, m.UpdatedAt, m.ID,
+			m.EmailVerified, m.Status, m.UpdatedAt, m.ID,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to update user: %w", err)
@@ -157,6 +230,103 @@ func (r *UserDB) GetByID(ctx context.Context, id int64) (*entities.User, error)
 	return user, nil
 }
 
+// SetEmailActivationKey stores a newly sent activation key along with when
+// it was sent.
+func (r *UserDB) SetEmailActivationKey(ctx context.Context, id int64, key string, sentAt time.Time) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, setActivationSQL, key, sentAt, id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("set email activation key: %w", err)
+	}
+	return nil
+}
+
+// ClaimEmailActivation uses up one of the maxAttempts guesses allowed at the
+// user's activation key and returns the key and when it was sent. It
+// returns ErrActivationExhausted when there is no key left to guess.
+func (r *UserDB) ClaimEmailActivation(ctx context.Context, id int64, maxAttempts int) (string, time.Time, error) {
+	var (
+		key    string
+		sentAt time.Time
+	)
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, claimActivationSQL, id, maxAttempts).Scan(&key, &sentAt)
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", time.Time{}, ErrActivationExhausted
+		}
+		return "", time.Time{}, fmt.Errorf("claim email activation: %w", err)
+	}
+	return key, sentAt, nil
+}
+
+// VerifyEmail marks the user's email verified and activates the account.
+func (r *UserDB) VerifyEmail(ctx context.Context, id int64) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, verifyEmailSQL, time.Now(), id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("verify email: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword sets a new password hash and deactivates every session of
+// the user other than keepSessionID.
+func (r *UserDB) ChangePassword(ctx context.Context, id int64, passwordHash string, keepSessionID int64) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		return setPassword(ctx, tx, id, passwordHash, keepSessionID, time.Now())
+	}); err != nil {
+		return fmt.Errorf("change password: %w", err)
+	}
+	return nil
+}
+
+// SetRole changes what the user is allowed to do. It applies from their
+// next request, since sessions read the role from the database.
+func (r *UserDB) SetRole(ctx context.Context, id int64, role entities.UserRole) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, setRoleSQL, role, time.Now(), id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("set user role: %w", err)
+	}
+	return nil
+}
+
+// SetStatus changes the user's status. Suspending a user also logs out all
+// of their sessions.
+func (r *UserDB) SetStatus(ctx context.Context, id int64, status entities.UserStatus) error {
+	now := time.Now()
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, setStatusSQL, status, now, id); err != nil {
+			return fmt.Errorf("failed to set status: %w", err)
+		}
+
+		if status == entities.UserStatusInactive {
+			if _, err := tx.Exec(ctx, deactivateOtherSessionsSQL, now, id, 0); err != nil {
+				return fmt.Errorf("failed to deactivate sessions: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("set user status: %w", err)
+	}
+	return nil
+}
+
+func setPassword(ctx context.Context, tx pgx.Tx, userID int64, passwordHash string, keepSessionID int64, now time.Time) error {
+	if _, err := tx.Exec(ctx, setPasswordSQL, passwordHash, now, userID); err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, deactivateOtherSessionsSQL, now, userID, keepSessionID); err != nil {
+		return fmt.Errorf("failed to deactivate sessions: %w", err)
+	}
+	return nil
+}
+
 func (u *UserDB) Delete(ctx context.Context, id int64) error {
 	if err := u.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		_, err := tx.Exec(ctx, deleteUserSQL, id)
@@ -170,6 +340,8 @@ func (u *UserDB) Delete(ctx context.Context, id int64) error {
 func (r *UserDB) buildQuery(
 	query string,
 	filter *webutils.Filter,
+	keys *keyset,
+	searchEmail bool,
 ) (string, []interface{}) {
 
 	conditions := make([]string, 0)
@@ -177,14 +349,16 @@ func (r *UserDB) buildQuery(
 	counter := util.NewPlaceholder()
 
 	if filter.Term != "" {
-		filterColumns := []string{"first_name", "last_name", "email"}
+		filterColumns := []string{"first_name", "last_name"}
+		if searchEmail {
+			filterColumns = append(filterColumns, "email")
+		}
 		likeStatements := make([]string, 0)
 
-		args = append(args, strings.ToLower(filter.Term))
+		args = append(args, escapeLike(filter.Term))
 		termPlaceholder := counter.Touch()
 		for _, col := range filterColumns {
-			stmt := fmt.Sprintf("lower(%s) LIKE '%%' || $%d || '%%'", col, termPlaceholder)
-			likeStatements = append(likeStatements, stmt)
+			likeStatements = append(likeStatements, containsSQL(fmt.Sprintf("lower(%s)", col), termPlaceholder))
 		}
 		condition := fmt.Sprintf(" (%s)", strings.Join(likeStatements, " OR "))
 		conditions = append(conditions, condition)
@@ -200,6 +374,12 @@ func (r *UserDB) buildQuery(
 		args = append(args, filter.FromTime.Time, filter.ToTime.Time)
 	}
 
+	if keys != nil {
+		condition, keyArgs := keys.condition(counter.Touch)
+		conditions = append(conditions, condition)
+		args = append(args, keyArgs...)
+	}
+
 	if len(conditions) > 0 {
 		query += " where" + strings.Join(conditions, " and ")
 	}
@@ -207,14 +387,16 @@ func (r *UserDB) buildQuery(
 	return query, args
 }
 
-func (r *UserDB) scan(row pgx.Row) (*entities.User, error) {
+// scan reads a user, followed by any extra columns into extra.
+func (r *UserDB) scan(row pgx.Row, extra ...interface{}) (*entities.User, error) {
 	user := entities.NewUser()
 
-	if err := row.Scan(
-		&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.EmailActivationKey,
-		&user.EmailVerified, &user.Status, &user.PasswordHash,
+	if err := row.Scan(append([]interface{}{
+		&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.EmailActivationKey, &user.EmailActivationAt,
+		&user.EmailVerified, &user.Status, &user.Role, &user.PasswordHash, &user.Reputation,
+		&user.TOTPSecret, &user.TOTPEnabledAt, &user.TOTPLastStep,
 		&user.Timestamps.CreatedAt, &user.Timestamps.UpdatedAt,
-	); err != nil {
+	}, extra...)...); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
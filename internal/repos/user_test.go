@@ -0,0 +1,26 @@
+package repos
+
+import (
+	"strings"
+	"testing"
+
+	"goquizbox/internal/web/webutils"
+)
+
+func TestUserBuildQuerySearchEmail(t *testing.T) {
+	db := &UserDB{}
+	filter := &webutils.Filter{Term: "ann"}
+
+	query, _ := db.buildQuery(countUsersSQL, filter, nil, false)
+	if strings.Contains(query, "email") {
+		t.Errorf("expected public searches to skip email, got %v", query)
+	}
+	if !strings.Contains(query, "lower(first_name)") || !strings.Contains(query, "lower(last_name)") {
+		t.Errorf("expected public searches to match names, got %v", query)
+	}
+
+	query, _ = db.buildQuery(countUsersSQL, filter, nil, true)
+	if !strings.Contains(query, "lower(email)") {
+		t.Errorf("expected admin searches to match email, got %v", query)
+	}
+}
@@ -7,16 +7,26 @@ import (
 	"goquizbox/internal/database"
 	"goquizbox/internal/entities"
 	"strings"
+	"time"
 
 	pgx "github.com/jackc/pgx/v4"
+	null "gopkg.in/guregu/null.v4"
 )
 
 const (
-	createVoteSQL              = `insert into votes (user_id, kind_id, kind, mode, created_at) values ($1, $2, $3, $4, $5) returning id`
-	selectVoteSQL              = `select id, user_id, kind_id, kind, mode, created_at from votes`
+	createVoteSQL = `insert into votes (user_id, kind_id, kind, mode, created_at) values ($1, $2, $3, $4, $5)
+		on conflict (user_id, kind_id, kind) do nothing returning id`
+	selectVoteSQL              = `select id, user_id, kind_id, kind, mode, created_at, updated_at from votes`
 	selectVoteByUserAndKindSQL = selectVoteSQL + ` where user_id = $1 and kind_id = $2 and kind = $3`
 	updateVoteSQL              = `update votes set (mode, updated_at) = ($1, $2) where id = $3`
 	countVotesSQL              = `select count(id) from votes where kind_id= $1 and kind = $2 and mode = $3`
+	deleteVoteSQL              = `delete from votes where id = $1 returning user_id, kind_id, kind::text, mode::text`
+	lockVoteSQL                = `select id, mode::text from votes where user_id = $1 and kind_id = $2 and kind = $3 for update`
+	summarizeVotesSQL          = `select kind_id,
+		count(id) filter (where mode = 'up'),
+		count(id) filter (where mode = 'down'),
+		max(mode::text) filter (where user_id = $3)
+		from votes where kind = $1 and kind_id = any($2) group by kind_id`
 )
 
 type VoteDB struct {
@@ -29,6 +39,10 @@ func NewVoteDB(db *database.DB) *VoteDB {
 	}
 }
 
+// Save casts the user's vote on an item, replacing any vote they already
+// cast there, and moves the reputation it earns in the same transaction.
+// Votes are keyed on (user_id, kind_id, kind) so m.ID is filled in rather
+// than read.
 func (r *VoteDB) Save(ctx context.Context, m *entities.Vote) error {
 	if errors := m.Validate(); len(errors) > 0 {
 		return fmt.Errorf("VoteDB invalid: %v", strings.Join(errors, ", "))
@@ -36,23 +50,51 @@ func (r *VoteDB) Save(ctx context.Context, m *entities.Vote) error {
 
 	m.Touch()
 	return r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		if m.IsNew() {
-			err := tx.QueryRow(
-				ctx, createVoteSQL, m.UserID, m.KindID, m.Kind, m.Mode, m.CreatedAt,
-			).Scan(&m.ID)
-			if err != nil {
-				return fmt.Errorf("inserting answer: %w", err)
-			}
+		previousMode, err := r.upsert(ctx, tx, m)
+		if err != nil {
+			return err
+		}
+
+		if previousMode == m.Mode {
 			return nil
 		}
-		_, err := tx.Exec(
-			ctx, updateVoteSQL, m.Mode, m.UpdatedAt, m.ID,
-		)
+		return updateVoteReputation(ctx, tx, m.Kind, m.KindID, m.UserID, previousMode, m.Mode)
+	})
+}
+
+// upsert writes the vote and returns the mode it replaced, if any. The
+// insert either creates the row or, once any concurrent insert of it has
+// committed, leaves it alone; the existing row is then locked before its
+// mode is read so concurrent votes cannot both see the same previous mode.
+func (*VoteDB) upsert(ctx context.Context, tx pgx.Tx, m *entities.Vote) (string, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		err := tx.QueryRow(ctx, createVoteSQL, m.UserID, m.KindID, m.Kind, m.Mode, m.CreatedAt).Scan(&m.ID)
+		if err == nil {
+			return "", nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("inserting vote: %w", err)
+		}
+
+		var previousMode string
+		err = tx.QueryRow(ctx, lockVoteSQL, m.UserID, m.KindID, m.Kind).Scan(&m.ID, &previousMode)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Retracted since the insert; try again.
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("failed to update vote: %w", err)
+			return "", fmt.Errorf("failed to get previous vote: %w", err)
 		}
-		return nil
-	})
+
+		if previousMode != m.Mode {
+			m.UpdatedAt = null.TimeFrom(time.Now())
+			if _, err := tx.Exec(ctx, updateVoteSQL, m.Mode, m.UpdatedAt, m.ID); err != nil {
+				return "", fmt.Errorf("failed to update vote: %w", err)
+			}
+		}
+		return previousMode, nil
+	}
+	return "", fmt.Errorf("vote on %v %v kept changing", m.Kind, m.KindID)
 }
 
 func (r *VoteDB) ByUserAndKind(
@@ -79,6 +121,72 @@ func (r *VoteDB) ByUserAndKind(
 	return vote, nil
 }
 
+// Delete retracts a vote along with the reputation it earned.
+func (r *VoteDB) Delete(ctx context.Context, id int64) error {
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		vote := entities.NewVote()
+		err := tx.QueryRow(ctx, deleteVoteSQL, id).Scan(&vote.UserID, &vote.KindID, &vote.Kind, &vote.Mode)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		return updateVoteReputation(ctx, tx, vote.Kind, vote.KindID, vote.UserID, vote.Mode, "")
+	}); err != nil {
+		return fmt.Errorf("delete vote by id: %w", err)
+	}
+	return nil
+}
+
+// Summaries returns the up, down and net score for each of the given items,
+// along with the vote userID cast on each. Items without votes get an empty
+// summary.
+func (r *VoteDB) Summaries(
+	ctx context.Context,
+	kind string,
+	kindIDs []int64,
+	userID int64,
+) (map[int64]*entities.VoteSummary, error) {
+	summaries := make(map[int64]*entities.VoteSummary, len(kindIDs))
+	for _, kindID := range kindIDs {
+		summaries[kindID] = entities.NewVoteSummary()
+	}
+
+	if len(kindIDs) == 0 {
+		return summaries, nil
+	}
+
+	if err := r.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, summarizeVotesSQL, kind, kindIDs, userID)
+		if err != nil {
+			return fmt.Errorf("failed to summarize votes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			var kindID int64
+			summary := entities.NewVoteSummary()
+			if err := rows.Scan(&kindID, &summary.Up, &summary.Down, &summary.UserVote); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			summary.Net = summary.Up - summary.Down
+			summaries[kindID] = summary
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("summarize votes: %w", err)
+	}
+
+	return summaries, nil
+}
+
 func (r *VoteDB) CountVotes(
 	ctx context.Context,
 	kindID int64,
@@ -103,7 +211,7 @@ func (*VoteDB) scan(row pgx.Row) (*entities.Vote, error) {
 	vote := entities.NewVote()
 
 	if err := row.Scan(
-		&vote.ID, &vote.UserID, &vote.KindID, &vote.Kind, &vote.Mode, &vote.CreatedAt,
+		&vote.ID, &vote.UserID, &vote.KindID, &vote.Kind, &vote.Mode, &vote.CreatedAt, &vote.UpdatedAt,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -5,11 +5,13 @@ import (
 	"context"
 
 	"goquizbox/internal/database"
+	"goquizbox/internal/mailer"
 )
 
 // ServerEnv represents latent environment configuration for servers in this application.
 type ServerEnv struct {
 	database *database.DB
+	mailer   mailer.Mailer
 }
 
 // Option defines function types to modify the ServerEnv on creation.
@@ -34,10 +36,22 @@ func WithDatabase(db *database.DB) Option {
 	}
 }
 
+// WithMailer attaches a mailer to the environment.
+func WithMailer(m mailer.Mailer) Option {
+	return func(s *ServerEnv) *ServerEnv {
+		s.mailer = m
+		return s
+	}
+}
+
 func (s *ServerEnv) Database() *database.DB {
 	return s.database
 }
 
+func (s *ServerEnv) Mailer() mailer.Mailer {
+	return s.mailer
+}
+
 // Close shuts down the server env, closing database connections, etc.
 func (s *ServerEnv) Close(ctx context.Context) error {
 	if s == nil {
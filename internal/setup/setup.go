@@ -7,6 +7,7 @@ import (
 
 	"goquizbox/internal/database"
 	"goquizbox/internal/logger"
+	"goquizbox/internal/mailer"
 	"goquizbox/internal/serverenv"
 
 	envconfig "github.com/sethvargo/go-envconfig"
@@ -18,6 +19,12 @@ type DatabaseConfigProvider interface {
 	DatabaseConfig() *database.Config
 }
 
+// MailerConfigProvider ensures that the environment config can provide a
+// mailer config.
+type MailerConfigProvider interface {
+	MailerConfig() *mailer.Config
+}
+
 // Setup runs common initialization code for all servers. See SetupWith.
 func Setup(ctx context.Context, config interface{}) (*serverenv.ServerEnv, error) {
 	return SetupWith(ctx, config, envconfig.OsLookuper())
@@ -62,5 +69,20 @@ func SetupWith(
 		logger.Infof("database config: %v", dbConfig)
 	}
 
+	// Setup the mailer.
+	if provider, ok := config.(MailerConfigProvider); ok {
+		logger.Info("configuring mailer")
+
+		mailerConfig := provider.MailerConfig()
+		m, err := mailer.New(mailerConfig)
+		if err != nil {
+			return nil, fmt.Errorf("unable to configure mailer: %w", err)
+		}
+
+		serverEnvOpts = append(serverEnvOpts, serverenv.WithMailer(m))
+
+		logger.Infof("mailer config: %v", mailerConfig)
+	}
+
 	return serverenv.New(ctx, serverEnvOpts...), nil
 }
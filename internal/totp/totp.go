@@ -0,0 +1,166 @@
+// Package totp implements time-based one-time passwords as described in
+// RFC 6238, compatible with the common authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPeriod is the step size authenticator apps assume.
+	DefaultPeriod = 30 * time.Second
+
+	// DefaultDigits is the code length authenticator apps assume.
+	DefaultDigits = 6
+
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type Algorithm string
+
+const (
+	AlgorithmSHA1   Algorithm = "SHA1"
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+func (a Algorithm) hash() func() hash.Hash {
+	switch a {
+	case AlgorithmSHA256:
+		return sha256.New
+	case AlgorithmSHA512:
+		return sha512.New
+	}
+	return sha1.New
+}
+
+// Options controls code generation. The zero value uses SHA1, 6 digits and
+// 30 second steps, which is what authenticator apps expect.
+type Options struct {
+	Algorithm Algorithm
+	Digits    int
+	Period    time.Duration
+	// Skew is the number of steps either side of the current one accepted
+	// to allow for clock drift.
+	Skew int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Algorithm == "" {
+		o.Algorithm = AlgorithmSHA1
+	}
+	if o.Digits == 0 {
+		o.Digits = DefaultDigits
+	}
+	if o.Period == 0 {
+		o.Period = DefaultPeriod
+	}
+	return o
+}
+
+// GenerateSecret returns a random base32 encoded secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return encoding.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// Step returns the time step t falls in.
+func Step(t time.Time, opts Options) int64 {
+	opts = opts.withDefaults()
+	return t.Unix() / int64(opts.Period/time.Second)
+}
+
+// Code returns the code for the given step as per RFC 4226 section 5.3.
+func Code(key []byte, step int64, opts Options) string {
+	opts = opts.withDefaults()
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(opts.Algorithm.hash(), key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < opts.Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", opts.Digits, value%mod)
+}
+
+// Generate returns the code for secret at time t.
+func Generate(secret string, t time.Time, opts Options) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	return Code(key, Step(t, opts), opts), nil
+}
+
+// Validate checks code against secret at time t, allowing opts.Skew steps
+// of drift. It returns the step that matched so callers can refuse to
+// accept the same step twice.
+func Validate(secret, code string, t time.Time, opts Options) (int64, bool) {
+	opts = opts.withDefaults()
+
+	code = strings.TrimSpace(code)
+	if len(code) != opts.Digits {
+		return 0, false
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := Step(t, opts)
+	for i := -opts.Skew; i <= opts.Skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(Code(key, step, opts)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the otpauth:// URI authenticator apps read from a QR code.
+func URI(issuer, account, secret string, opts Options) string {
+	opts = opts.withDefaults()
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", string(opts.Algorithm))
+	params.Set("digits", fmt.Sprint(opts.Digits))
+	params.Set("period", fmt.Sprint(int64(opts.Period/time.Second)))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B.
+func TestRFC6238Vectors(t *testing.T) {
+	t.Parallel()
+
+	seeds := map[Algorithm]string{
+		AlgorithmSHA1:   "12345678901234567890",
+		AlgorithmSHA256: "12345678901234567890123456789012",
+		AlgorithmSHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+
+	cases := []struct {
+		unix int64
+		algo Algorithm
+		want string
+	}{
+		{59, AlgorithmSHA1, "94287082"},
+		{59, AlgorithmSHA256, "46119246"},
+		{59, AlgorithmSHA512, "90693936"},
+		{1111111109, AlgorithmSHA1, "07081804"},
+		{1111111109, AlgorithmSHA256, "68084774"},
+		{1111111109, AlgorithmSHA512, "25091201"},
+		{1111111111, AlgorithmSHA1, "14050471"},
+		{1111111111, AlgorithmSHA256, "67062674"},
+		{1111111111, AlgorithmSHA512, "99943326"},
+		{1234567890, AlgorithmSHA1, "89005924"},
+		{1234567890, AlgorithmSHA256, "91819424"},
+		{1234567890, AlgorithmSHA512, "93441116"},
+		{2000000000, AlgorithmSHA1, "69279037"},
+		{2000000000, AlgorithmSHA256, "90698825"},
+		{2000000000, AlgorithmSHA512, "38618901"},
+		{20000000000, AlgorithmSHA1, "65353130"},
+		{20000000000, AlgorithmSHA256, "77737706"},
+		{20000000000, AlgorithmSHA512, "47863826"},
+	}
+
+	for _, tc := range cases {
+		opts := Options{Algorithm: tc.algo, Digits: 8}
+		secret := base32.StdEncoding.EncodeToString([]byte(seeds[tc.algo]))
+
+		got, err := Generate(secret, time.Unix(tc.unix, 0), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%v at %d: got %v, want %v", tc.algo, tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	opts := Options{Skew: 1}
+
+	code, err := Generate(secret, now.Add(-DefaultPeriod), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := Validate(secret, code, now, opts)
+	if !ok {
+		t.Fatal("code from the previous step was rejected")
+	}
+	if want := Step(now, opts) - 1; step != want {
+		t.Errorf("got step %d, want %d", step, want)
+	}
+
+	if _, ok := Validate(secret, code, now.Add(2*DefaultPeriod), opts); ok {
+		t.Error("code outside the skew window was accepted")
+	}
+
+	if _, ok := Validate(secret, "12345", now, opts); ok {
+		t.Error("short code was accepted")
+	}
+}
+
+func TestURI(t *testing.T) {
+	t.Parallel()
+
+	uri := URI("Quizbox", "jane@example.com", "JBSWY3DPEHPK3PXP", Options{})
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Quizbox:jane@example.com?") {
+		t.Errorf("unexpected label in %v", uri)
+	}
+	for _, part := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Quizbox", "digits=6", "period=30", "algorithm=SHA1"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("%v missing %v", uri, part)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"strings"
+)
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffCells bounds the size of the table DiffLines fills in, about 16MB
+// of int32s. Changes larger than this are shown as a plain replacement.
+const maxDiffCells = 1 << 22
+
+// DiffLines returns a line level diff that turns from into to, based on the
+// longest common subsequence of their lines. Unchanged lines at either end
+// are matched directly; if what remains between them is too large to
+// compare line by line, it is diffed as a delete of all of it followed by
+// an insert.
+func DiffLines(from, to string) []DiffLine {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	return lines
+}
+
+func diffMiddle(a, b []string) []DiffLine {
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: line})
+		}
+		return lines
+	}
+
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
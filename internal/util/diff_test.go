@@ -0,0 +1,103 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		from string
+		to   string
+		want []DiffLine
+	}{
+		{
+			name: "empty",
+			want: []DiffLine{},
+		},
+		{
+			name: "unchanged",
+			from: "a\nb",
+			to:   "a\nb",
+			want: []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}},
+		},
+		{
+			name: "added",
+			from: "",
+			to:   "a",
+			want: []DiffLine{{DiffInsert, "a"}},
+		},
+		{
+			name: "removed",
+			from: "a\nb",
+			to:   "b",
+			want: []DiffLine{{DiffDelete, "a"}, {DiffEqual, "b"}},
+		},
+		{
+			name: "changed",
+			from: "a\nb\nc",
+			to:   "a\nx\nc\nd",
+			want: []DiffLine{
+				{DiffEqual, "a"},
+				{DiffDelete, "b"},
+				{DiffInsert, "x"},
+				{DiffEqual, "c"},
+				{DiffInsert, "d"},
+			},
+		},
+		{
+			name: "unchanged ends",
+			from: "a\nb\nc\nd",
+			to:   "a\nc\nd",
+			want: []DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffEqual, "c"}, {DiffEqual, "d"}},
+		},
+		{
+			name: "crlf",
+			from: "a\r\nb",
+			to:   "a\nb",
+			want: []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := DiffLines(tc.from, tc.to)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	t.Parallel()
+
+	var from, to []string
+	for i := 0; i < 3000; i++ {
+		from = append(from, fmt.Sprintf("old %d", i))
+		to = append(to, fmt.Sprintf("new %d", i))
+	}
+	from = append([]string{"title"}, from...)
+	to = append([]string{"title"}, to...)
+
+	got := DiffLines(strings.Join(from, "\n"), strings.Join(to, "\n"))
+	if len(got) != 1+3000+3000 {
+		t.Fatalf("got %d lines, want %d", len(got), 1+3000+3000)
+	}
+	if got[0] != (DiffLine{DiffEqual, "title"}) {
+		t.Errorf("expected the shared first line to be kept, got %v", got[0])
+	}
+	if got[1].Op != DiffDelete || got[3000].Op != DiffDelete || got[3001].Op != DiffInsert || got[6000].Op != DiffInsert {
+		t.Errorf("expected the rest to be replaced wholesale, got %v, %v, %v, %v", got[1], got[3000], got[3001], got[6000])
+	}
+}
@@ -30,8 +30,10 @@ func GenerateApiKeyPassword() string {
 	return generateRandomString(digits+lowerCaseLetters+upperCaseLetters, 40)
 }
 
-func GenerateEmailActivationKey() string {
-	return generateRandomString(digits+lowerCaseLetters+upperCaseLetters, 5)
+// GenerateEmailActivationKey returns a code to verify an email address
+// with. It is not case sensitive.
+func GenerateEmailActivationKey() (string, error) {
+	return GenerateSecureCode(digits+upperCaseLetters, 10)
 }
 
 func GenerateEmailConfirmationKey() string {
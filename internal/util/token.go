@@ -0,0 +1,40 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+)
+
+// GenerateSecureToken returns a URL safe token carrying size random bytes.
+func GenerateSecureToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateSecureCode returns length characters picked uniformly at random
+// from charset, for codes people type in.
+func GenerateSecureCode(charset string, length int) (string, error) {
+	max := big.NewInt(int64(len(charset)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = charset[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token, for storing tokens
+// that only need to be looked up and never read back.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
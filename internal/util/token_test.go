@@ -0,0 +1,26 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateEmailActivationKey(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		key, err := GenerateEmailActivationKey()
+		if err != nil {
+			t.Fatalf("GenerateEmailActivationKey: %v", err)
+		}
+		if len(key) != 10 {
+			t.Errorf("got key %q of length %d, want 10", key, len(key))
+		}
+		if strings.Trim(key, digits+upperCaseLetters) != "" {
+			t.Errorf("got key %q with characters outside the charset", key)
+		}
+		if seen[key] {
+			t.Errorf("got key %q twice", key)
+		}
+		seen[key] = true
+	}
+}
@@ -0,0 +1,142 @@
+package views
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"goquizbox/internal/worker"
+)
+
+// Store persists batches of question views.
+type Store interface {
+	AddViews(ctx context.Context, views map[int64]int64) error
+}
+
+// Counter counts question views at most once per viewer per window. Views
+// are buffered in memory and written to the store in batches by Flush, so
+// recording a view never waits on the database.
+//
+// Deduplication is per process: each instance remembers its own viewers,
+// so behind a load balancer a viewer can be counted once per instance, and
+// a restart forgets everyone. At most maxViewers are remembered; past that
+// the least recently seen are forgotten early and may be counted again.
+type Counter struct {
+	store      Store
+	window     time.Duration
+	maxViewers int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	seen    map[string]*list.Element
+	order   *list.List // of *seenViewer, least recently seen first
+	pending map[int64]int64
+}
+
+type seenViewer struct {
+	key  string
+	last time.Time
+}
+
+func NewCounter(store Store, window time.Duration, maxViewers int) *Counter {
+	return &Counter{
+		store:      store,
+		window:     window,
+		maxViewers: maxViewers,
+		now:        time.Now,
+		seen:       make(map[string]*list.Element),
+		order:      list.New(),
+		pending:    make(map[int64]int64),
+	}
+}
+
+// Viewer identifies who is viewing a question: the user when signed in,
+// otherwise a hash of their IP address and user agent. ipAddress must be
+// the trusted client IP so anonymous viewers cannot pick a fresh identity
+// per request.
+func Viewer(userID int64, ipAddress, userAgent string) string {
+	if userID > 0 {
+		return fmt.Sprintf("user:%d", userID)
+	}
+
+	sum := sha256.Sum256([]byte(ipAddress + "\x00" + userAgent))
+	return "anon:" + hex.EncodeToString(sum[:])
+}
+
+// Record counts a view of the question by viewer unless they already viewed
+// it within the window. It reports whether the view was counted.
+func (c *Counter) Record(questionID int64, viewer string) bool {
+	key := fmt.Sprintf("%d:%s", questionID, viewer)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.forgetExpired(now)
+
+	if elem, ok := c.seen[key]; ok {
+		if now.Sub(elem.Value.(*seenViewer).last) < c.window {
+			return false
+		}
+		c.order.Remove(elem)
+		delete(c.seen, key)
+	}
+
+	for c.order.Len() >= c.maxViewers && c.order.Len() > 0 {
+		oldest := c.order.Remove(c.order.Front()).(*seenViewer)
+		delete(c.seen, oldest.key)
+	}
+
+	c.seen[key] = c.order.PushBack(&seenViewer{key: key, last: now})
+	c.pending[questionID]++
+	return true
+}
+
+// forgetExpired drops viewers whose window has passed. Viewers are kept in
+// the order they were seen, so it stops at the first one still in window.
+func (c *Counter) forgetExpired(now time.Time) {
+	for elem := c.order.Front(); elem != nil; elem = c.order.Front() {
+		viewer := elem.Value.(*seenViewer)
+		if now.Sub(viewer.last) < c.window {
+			return
+		}
+		c.order.Remove(elem)
+		delete(c.seen, viewer.key)
+	}
+}
+
+// Flush writes the buffered views to the store and forgets viewers whose
+// window has passed. Views that fail to write are kept for the next flush.
+func (c *Counter) Flush(ctx context.Context) error {
+	now := c.now()
+
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[int64]int64)
+	c.forgetExpired(now)
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := c.store.AddViews(ctx, pending); err != nil {
+		c.mu.Lock()
+		for questionID, count := range pending {
+			c.pending[questionID] += count
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("flush question views: %w", err)
+	}
+	return nil
+}
+
+// Run flushes every interval until ctx is done. Callers should Flush once
+// more after it returns so views recorded during shutdown are not lost.
+func (c *Counter) Run(ctx context.Context, interval time.Duration) {
+	worker.Every(ctx, "flush question views", interval, c.Flush)
+}
@@ -0,0 +1,127 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	batches []map[int64]int64
+	err     error
+}
+
+func (s *fakeStore) AddViews(_ context.Context, views map[int64]int64) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, views)
+	return nil
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 2, 4, 9, 0, 0, 0, time.UTC)
+	store := &fakeStore{}
+	counter := NewCounter(store, 30*time.Minute, 100)
+	counter.now = func() time.Time { return now }
+
+	alice := Viewer(1, "10.0.0.1", "firefox")
+	anon := Viewer(0, "10.0.0.1", "firefox")
+
+	if !counter.Record(10, alice) || !counter.Record(10, anon) || !counter.Record(11, alice) {
+		t.Fatal("expected first views to be counted")
+	}
+	if counter.Record(10, alice) {
+		t.Error("expected repeat view within the window to be ignored")
+	}
+
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if want := []map[int64]int64{{10: 2, 11: 1}}; !reflect.DeepEqual(store.batches, want) {
+		t.Errorf("got batches %v, want %v", store.batches, want)
+	}
+
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(store.batches) != 1 {
+		t.Errorf("expected empty flush to skip the store, got %d batches", len(store.batches))
+	}
+
+	now = now.Add(30 * time.Minute)
+	if !counter.Record(10, alice) {
+		t.Error("expected view after the window to be counted")
+	}
+
+	store.err = errors.New("database down")
+	if err := counter.Flush(ctx); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	store.err = nil
+	counter.Record(11, anon)
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if want := (map[int64]int64{10: 1, 11: 1}); !reflect.DeepEqual(store.batches[1], want) {
+		t.Errorf("expected failed views to be retried, got %v", store.batches[1])
+	}
+}
+
+func TestCounterMaxViewers(t *testing.T) {
+	now := time.Date(2023, 2, 4, 9, 0, 0, 0, time.UTC)
+	counter := NewCounter(&fakeStore{}, 30*time.Minute, 2)
+	counter.now = func() time.Time { return now }
+
+	for _, viewer := range []string{"a", "b", "c"} {
+		if !counter.Record(10, viewer) {
+			t.Fatalf("expected first view by %q to be counted", viewer)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	if got := len(counter.seen); got != 2 {
+		t.Errorf("remembered %d viewers, want 2", got)
+	}
+	if counter.Record(10, "c") || counter.Record(10, "b") {
+		t.Error("expected recent viewers to still be remembered")
+	}
+	if !counter.Record(10, "a") {
+		t.Error("expected the oldest viewer to be forgotten at the cap")
+	}
+}
+
+func TestCounterForgetsExpiredViewers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 2, 4, 9, 0, 0, 0, time.UTC)
+	counter := NewCounter(&fakeStore{}, 30*time.Minute, 100)
+	counter.now = func() time.Time { return now }
+
+	counter.Record(10, "a")
+	now = now.Add(20 * time.Minute)
+	counter.Record(10, "b")
+	now = now.Add(15 * time.Minute)
+
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if _, ok := counter.seen["10:a"]; ok {
+		t.Error("expected expired viewer to be forgotten")
+	}
+	if _, ok := counter.seen["10:b"]; !ok {
+		t.Error("expected viewer within the window to be remembered")
+	}
+}
+
+func TestViewer(t *testing.T) {
+	if got := Viewer(7, "10.0.0.1", "firefox"); got != "user:7" {
+		t.Errorf("got %q", got)
+	}
+	if Viewer(0, "10.0.0.1", "firefox") == Viewer(0, "10.0.0.1", "chrome") {
+		t.Error("expected user agent to distinguish anonymous viewers")
+	}
+}
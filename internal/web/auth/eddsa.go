@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var errEdDSAVerification = errors.New("eddsa: verification error")
+
+// signingMethodEdDSA implements Ed25519 signatures, which jwt-go v3 lacks.
+type signingMethodEdDSA struct{}
+
+var SigningMethodEdDSA jwt.SigningMethod = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return errEdDSAVerification
+	}
+	return nil
+}
+
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
@@ -2,7 +2,7 @@ package auth
 
 import (
 	"encoding/json"
-	"os"
+	"fmt"
 	"time"
 
 	"goquizbox/internal/entities"
@@ -18,26 +18,31 @@ type (
 	}
 
 	AppJWTHandler struct {
-		keyFunc    func(*jwt.Token) (interface{}, error)
-		signingKey []byte
+		keys *KeySet
 	}
 )
 
-func NewJWTHandler() JWTHandler {
-	return NewJWTHandlerWithSigningKey(os.Getenv("JWT_SIGNING_KEY"))
+func NewJWTHandler(keys *KeySet) JWTHandler {
+	return &AppJWTHandler{
+		keys: keys,
+	}
 }
 
-func NewJWTHandlerWithSigningKey(signingKey string) JWTHandler {
+// keyFunc picks the verification key named by the token's kid header and
+// rejects tokens whose alg does not belong to that key.
+func (h *AppJWTHandler) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
 
-	handler := &AppJWTHandler{
-		signingKey: []byte(signingKey),
+	key, ok := h.keys.PublicKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
 	}
 
-	handler.keyFunc = func(*jwt.Token) (interface{}, error) {
-		return handler.signingKey, nil
+	if method := signingMethod(key); method == nil || method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v for key %q", token.Method.Alg(), kid)
 	}
 
-	return handler
+	return key, nil
 }
 
 func (h *AppJWTHandler) CreateUserToken(
@@ -45,19 +50,21 @@ func (h *AppJWTHandler) CreateUserToken(
 	session *entities.Session,
 ) (string, error) {
 
-	token := jwt.New(jwt.SigningMethodHS256)
+	token := jwt.New(signingMethod(h.keys.signingKey.Public()))
+	token.Header["kid"] = h.keys.signingKeyID
 
 	claims := make(jwt.MapClaims, 0)
 
 	claims["exp"] = time.Now().AddDate(1, 0, 0).Unix()
 	claims["refresh"] = time.Now().Add(time.Hour).Unix()
+	claims["role"] = user.Role.String()
 	claims["session_id"] = session.ID
 	claims["status"] = user.Status.String()
 	claims["user_id"] = user.ID
 
 	token.Claims = claims
 
-	return token.SignedString(h.signingKey)
+	return token.SignedString(h.keys.signingKey)
 }
 
 func (h *AppJWTHandler) TokenInfo(tokenValue string) (*entities.TokenInfo, error) {
@@ -74,6 +81,7 @@ func (h *AppJWTHandler) TokenInfo(tokenValue string) (*entities.TokenInfo, error
 
 	exp := h.getInt64(mapClaims, "exp")
 	refresh := h.getInt64(mapClaims, "refresh")
+	role := h.getString(mapClaims, "role")
 	sessionID := h.getInt64(mapClaims, "session_id")
 	status := h.getString(mapClaims, "status")
 	userID := h.getInt64(mapClaims, "user_id")
@@ -81,6 +89,7 @@ func (h *AppJWTHandler) TokenInfo(tokenValue string) (*entities.TokenInfo, error
 	tokenInfo := &entities.TokenInfo{
 		Exp:       time.Unix(exp, 0),
 		Refresh:   time.Unix(refresh, 0),
+		Role:      role,
 		SessionID: sessionID,
 		Status:    status,
 		UserID:    userID,
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goquizbox/internal/entities"
+)
+
+func writeKey(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+keyFileExt), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeEd25519Key(t *testing.T, dir, kid string) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, kid, "PRIVATE KEY", der)
+}
+
+func writeRSAKey(t *testing.T, dir, kid string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, kid, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+}
+
+func mustHandler(t *testing.T, config *JWTConfig) JWTHandler {
+	t.Helper()
+
+	keys, err := LoadKeySet(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewJWTHandler(keys)
+}
+
+func TestJWTRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "ed-1")
+	writeRSAKey(t, dir, "rsa-1")
+
+	user := &entities.User{Status: entities.UserStatusActive}
+	user.ID = 7
+	session := &entities.Session{}
+	session.ID = 11
+
+	for _, kid := range []string{"ed-1", "rsa-1"} {
+		handler := mustHandler(t, &JWTConfig{KeysDir: dir, SigningKeyID: kid})
+
+		token, err := handler.CreateUserToken(user, session)
+		if err != nil {
+			t.Fatalf("%v: CreateUserToken: %v", kid, err)
+		}
+
+		info, err := handler.TokenInfo(token)
+		if err != nil {
+			t.Fatalf("%v: TokenInfo: %v", kid, err)
+		}
+		if info.UserID != 7 || info.SessionID != 11 {
+			t.Errorf("%v: got user %v session %v", kid, info.UserID, info.SessionID)
+		}
+	}
+}
+
+func TestJWTRotation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "old")
+	writeEd25519Key(t, dir, "new")
+
+	user := &entities.User{}
+	session := &entities.Session{}
+
+	token, err := mustHandler(t, &JWTConfig{KeysDir: dir, SigningKeyID: "old"}).CreateUserToken(user, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := mustHandler(t, &JWTConfig{KeysDir: dir, SigningKeyID: "new"})
+	if _, err := rotated.TokenInfo(token); err != nil {
+		t.Errorf("token signed with the previous key was rejected: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "old"+keyFileExt)); err != nil {
+		t.Fatal(err)
+	}
+	retired := mustHandler(t, &JWTConfig{KeysDir: dir, SigningKeyID: "new"})
+	if _, err := retired.TokenInfo(token); err == nil {
+		t.Error("token signed with a removed key was accepted")
+	}
+}
+
+func TestJWTRejectsUnsignedAndForeignTokens(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "ours")
+	handler := mustHandler(t, &JWTConfig{KeysDir: dir})
+
+	unsigned := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIiwia2lkIjoib3VycyJ9.eyJ1c2VyX2lkIjoxfQ."
+	if _, err := handler.TokenInfo(unsigned); err == nil {
+		t.Error("alg=none token was accepted")
+	}
+
+	otherDir := t.TempDir()
+	writeEd25519Key(t, otherDir, "ours")
+	foreign, err := mustHandler(t, &JWTConfig{KeysDir: otherDir}).CreateUserToken(&entities.User{}, &entities.Session{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.TokenInfo(foreign); err == nil {
+		t.Error("token signed by another key with the same kid was accepted")
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	t.Parallel()
+
+	empty := t.TempDir()
+	if _, err := LoadKeySet(&JWTConfig{KeysDir: empty}); err == nil {
+		t.Error("expected an error for a directory without keys")
+	}
+
+	if _, err := LoadKeySet(&JWTConfig{}); err == nil {
+		t.Error("expected an error without a keys dir")
+	}
+
+	two := t.TempDir()
+	writeEd25519Key(t, two, "a")
+	writeEd25519Key(t, two, "b")
+	if _, err := LoadKeySet(&JWTConfig{KeysDir: two}); err == nil {
+		t.Error("expected an error when the signing key is ambiguous")
+	}
+	if _, err := LoadKeySet(&JWTConfig{KeysDir: two, SigningKeyID: "c"}); err == nil {
+		t.Error("expected an error for an unknown signing key")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "b-ed")
+	writeRSAKey(t, dir, "a-rsa")
+
+	keys, err := LoadKeySet(&JWTConfig{KeysDir: dir, SigningKeyID: "b-ed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(jwks.Keys))
+	}
+
+	rsaKey, edKey := jwks.Keys[0], jwks.Keys[1]
+	if rsaKey.Kid != "a-rsa" || rsaKey.Kty != "RSA" || rsaKey.Alg != "RS256" || rsaKey.E != "AQAB" || rsaKey.N == "" {
+		t.Errorf("unexpected rsa jwk %+v", rsaKey)
+	}
+	if edKey.Kid != "b-ed" || edKey.Kty != "OKP" || edKey.Crv != "Ed25519" || edKey.Alg != "EdDSA" || edKey.X == "" {
+		t.Errorf("unexpected ed25519 jwk %+v", edKey)
+	}
+}
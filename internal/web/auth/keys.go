@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const keyFileExt = ".pem"
+
+// JWTConfig points at the directory holding the token keys. Every
+// <kid>.pem file in it is trusted for verification; private keys can also
+// sign. Rotating means adding the new key, switching JWT_SIGNING_KEY_ID to
+// it and removing the old file once the tokens it signed have expired.
+type JWTConfig struct {
+	KeysDir      string `env:"JWT_KEYS_DIR, required"`
+	SigningKeyID string `env:"JWT_SIGNING_KEY_ID"`
+}
+
+type (
+	KeySet struct {
+		signingKeyID string
+		signingKey   crypto.Signer
+		publicKeys   map[string]crypto.PublicKey
+	}
+
+	JWK struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+	}
+
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+)
+
+// LoadKeySet reads the keys in config.KeysDir. It fails unless a private
+// key is available to sign with.
+func LoadKeySet(config *JWTConfig) (*KeySet, error) {
+	if config.KeysDir == "" {
+		return nil, fmt.Errorf("jwt keys dir is not set")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(config.KeysDir, "*"+keyFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jwt keys: %w", err)
+	}
+
+	keySet := &KeySet{
+		publicKeys: make(map[string]crypto.PublicKey, len(paths)),
+	}
+	signers := make(map[string]crypto.Signer)
+
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), keyFileExt)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwt key %v: %w", kid, err)
+		}
+
+		key, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwt key %v: %w", kid, err)
+		}
+
+		switch key := key.(type) {
+		case crypto.Signer:
+			signers[kid] = key
+			keySet.publicKeys[kid] = key.Public()
+		default:
+			keySet.publicKeys[kid] = key
+		}
+	}
+
+	keySet.signingKeyID = config.SigningKeyID
+	if keySet.signingKeyID == "" {
+		if len(signers) != 1 {
+			return nil, fmt.Errorf("found %d private jwt keys in %v, set JWT_SIGNING_KEY_ID to pick one", len(signers), config.KeysDir)
+		}
+		for kid := range signers {
+			keySet.signingKeyID = kid
+		}
+	}
+
+	signer, ok := signers[keySet.signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("no private jwt key %q in %v", keySet.signingKeyID, config.KeysDir)
+	}
+	keySet.signingKey = signer
+
+	return keySet, nil
+}
+
+func parseKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey, ed25519.PrivateKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", key)
+}
+
+// signingMethod returns the algorithm used with key, or nil for a key type
+// we do not sign with.
+func signingMethod(key crypto.PublicKey) jwt.SigningMethod {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		return SigningMethodEdDSA
+	}
+	return nil
+}
+
+// PublicKey returns the verification key for kid.
+func (k *KeySet) PublicKey(kid string) (crypto.PublicKey, bool) {
+	key, ok := k.publicKeys[kid]
+	return key, ok
+}
+
+// JWKS lists the verification keys in JSON Web Key Set form, ordered by kid.
+func (k *KeySet) JWKS() *JWKS {
+	kids := make([]string, 0, len(k.publicKeys))
+	for kid := range k.publicKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := &JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		jwk := JWK{Kid: kid, Use: "sig"}
+
+		switch key := k.publicKeys[kid].(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.Alg = jwt.SigningMethodRS256.Alg()
+			jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Alg = SigningMethodEdDSA.Alg()
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
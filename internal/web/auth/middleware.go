@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"goquizbox/internal/entities"
 	"goquizbox/internal/logger"
 	"goquizbox/internal/repos"
 	"goquizbox/internal/serverenv"
@@ -34,6 +37,15 @@ func AllowOnlyActiveUser(
 
 		tokenInfo := ctxhelper.TokenInfo(ctx)
 
+		if !tokenInfo.CanWrite() && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "this api key is read-only",
+			})
+			c.Abort()
+			return
+		}
+
 		if tokenInfo.RequiresRefresh() {
 			_, err = sessionAuthenticator.RefreshTokenFromRequest(ctx, tokenInfo, c.Writer)
 			if err != nil {
@@ -69,6 +81,74 @@ func AllowWithSession(
 	}
 }
 
+// RequireSession rejects requests made with an API key, for actions such as
+// managing credentials that need a logged in user.
+func RequireSession() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if ctxhelper.TokenInfo(c.Request.Context()).IsApiKey() {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "this action is not available to api keys, you have to log in",
+			})
+			c.Abort()
+			return
+		}
+	}
+}
+
+// RequireRole allows only users with at least role. It must run after
+// AllowOnlyActiveUser, which loads the current role.
+func RequireRole(role entities.UserRole) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !ctxhelper.TokenInfo(c.Request.Context()).HasRole(role) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("this action requires the '%v' role", role),
+			})
+			c.Abort()
+			return
+		}
+	}
+}
+
+// HasVerifiedRole is for public routes that show more to privileged
+// callers. Their token has not been checked against the session yet, so
+// the role it claims cannot be trusted on its own.
+func HasVerifiedRole(c *gin.Context, env *serverenv.ServerEnv, role entities.UserRole) bool {
+	tokenInfo := ctxhelper.TokenInfo(c.Request.Context())
+	if tokenInfo.UserID == 0 || !tokenInfo.HasRole(role) {
+		return false
+	}
+
+	if err := validateSession(c, nil, env); err != nil {
+		return false
+	}
+
+	return tokenInfo.HasRole(role)
+}
+
+// VerifiedUserID is for public routes that show callers more about
+// themselves. It returns the caller's user ID once their token has been
+// checked against an active session, and 0 for anonymous callers or tokens
+// whose session was revoked or expired.
+func VerifiedUserID(c *gin.Context, env *serverenv.ServerEnv) int64 {
+	tokenInfo := ctxhelper.TokenInfo(c.Request.Context())
+	if tokenInfo.UserID == 0 {
+		return 0
+	}
+
+	if err := validateSession(c, nil, env); err != nil {
+		return 0
+	}
+
+	return tokenInfo.UserID
+}
+
+// fullSessionByID loads the session behind a token, tests replace it.
+var fullSessionByID = func(ctx context.Context, env *serverenv.ServerEnv, id int64) (*entities.FullSession, error) {
+	return repos.NewSessionDB(env.Database()).GetFullSessionByID(ctx, id)
+}
+
 func validateSession(
 	c *gin.Context,
 	sessionAuthenticator SessionAuthenticator,
@@ -78,10 +158,15 @@ func validateSession(
 	ctx := c.Request.Context()
 	tokenInfo := ctxhelper.TokenInfo(ctx)
 
+	// API keys and their owners are checked by ApiKeyAuthenticator as the
+	// request comes in, there is no session behind them.
+	if tokenInfo.IsApiKey() {
+		return nil
+	}
+
 	// TODO: Add caching here to prevent db lookup each time
 
-	sessionDB := repos.NewSessionDB(env.Database())
-	session, err := sessionDB.GetFullSessionByID(ctx, tokenInfo.SessionID)
+	session, err := fullSessionByID(ctx, env, tokenInfo.SessionID)
 	if err != nil {
 		return fmt.Errorf("failed to get full session for id %v - %v", tokenInfo.SessionID, err)
 	}
@@ -98,9 +183,13 @@ func validateSession(
 		return fmt.Errorf("user=[%v] is inactive", tokenInfo.UserID)
 	}
 
-	if session.DeactivatedAt.Valid {
+	if !session.IsActive(time.Now()) {
 		return fmt.Errorf("sessionID=[%v] is not active", tokenInfo.SessionID)
 	}
 
+	// The role in the token may predate a change by an admin, the database
+	// has the current one.
+	tokenInfo.Role = session.UserRole.String()
+
 	return nil
 }
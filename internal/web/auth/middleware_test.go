@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/serverenv"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+	null "gopkg.in/guregu/null.v4"
+)
+
+func TestVerifiedUserID(t *testing.T) {
+	now := time.Now()
+	sessions := map[int64]*entities.FullSession{
+		1: {ID: 1, UserID: 7, UserStatus: entities.UserStatusActive, UserRole: entities.UserRoleUser},
+		2: {ID: 2, UserID: 7, UserStatus: entities.UserStatusActive, DeactivatedAt: null.TimeFrom(now.Add(-time.Minute))},
+		3: {ID: 3, UserID: 7, UserStatus: entities.UserStatusActive, ExpiresAt: null.TimeFrom(now.Add(-time.Minute))},
+		4: {ID: 4, UserID: 7, UserStatus: entities.UserStatusInactive},
+		5: {ID: 5, UserID: 8, UserStatus: entities.UserStatusActive},
+	}
+
+	lookup := fullSessionByID
+	t.Cleanup(func() { fullSessionByID = lookup })
+	fullSessionByID = func(_ context.Context, _ *serverenv.ServerEnv, id int64) (*entities.FullSession, error) {
+		return sessions[id], nil
+	}
+
+	cases := []struct {
+		name      string
+		tokenInfo *entities.TokenInfo
+		want      int64
+	}{
+		{name: "anonymous", want: 0},
+		{name: "active session", tokenInfo: &entities.TokenInfo{UserID: 7, SessionID: 1}, want: 7},
+		{name: "revoked session", tokenInfo: &entities.TokenInfo{UserID: 7, SessionID: 2}, want: 0},
+		{name: "expired session", tokenInfo: &entities.TokenInfo{UserID: 7, SessionID: 3}, want: 0},
+		{name: "inactive user", tokenInfo: &entities.TokenInfo{UserID: 7, SessionID: 4}, want: 0},
+		{name: "another user's session", tokenInfo: &entities.TokenInfo{UserID: 7, SessionID: 5}, want: 0},
+		{name: "missing session", tokenInfo: &entities.TokenInfo{UserID: 7, SessionID: 9}, want: 0},
+		{name: "api key", tokenInfo: &entities.TokenInfo{UserID: 7, ApiKeyID: 3}, want: 7},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/users/7", nil)
+			if tc.tokenInfo != nil {
+				c.Request = c.Request.WithContext(ctxhelper.WithTokenInfo(c.Request.Context(), tc.tokenInfo))
+			}
+
+			if got := VerifiedUserID(c, nil); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
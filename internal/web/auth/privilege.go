@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"goquizbox/internal/entities"
+	"goquizbox/internal/logger"
+	"goquizbox/internal/repos"
+	"goquizbox/internal/serverenv"
+	"goquizbox/internal/web/ctxhelper"
+
+	"github.com/gin-gonic/gin"
+)
+
+const privilegeKeyPrefix = "privilege:"
+
+// PrivilegeConfig holds the reputation needed for each privilege.
+type PrivilegeConfig struct {
+	Downvote    int `env:"PRIVILEGE_DOWNVOTE, default=125"`
+	Comment     int `env:"PRIVILEGE_COMMENT, default=50"`
+	EditOthers  int `env:"PRIVILEGE_EDIT_OTHERS, default=2000"`
+	VoteToClose int `env:"PRIVILEGE_VOTE_TO_CLOSE, default=3000"`
+}
+
+func (c *PrivilegeConfig) Threshold(privilege entities.Privilege) int {
+	switch privilege {
+	case entities.PrivilegeDownvote:
+		return c.Downvote
+	case entities.PrivilegeComment:
+		return c.Comment
+	case entities.PrivilegeEditOthers:
+		return c.EditOthers
+	case entities.PrivilegeVoteToClose:
+		return c.VoteToClose
+	}
+	return 0
+}
+
+// OwnerLookup returns the author of the resource a request acts on, nil if
+// it does not exist.
+type OwnerLookup func(c *gin.Context) (*int64, error)
+
+// RequirePrivilege only lets through users whose reputation reaches the
+// threshold for privilege. When owner is given, the author of the resource
+// does not need the privilege. Users let through on reputation are marked so
+// handlers can tell with PrivilegeGranted. It must run after
+// AllowOnlyActiveUser.
+func RequirePrivilege(
+	privilege entities.Privilege,
+	config *PrivilegeConfig,
+	env *serverenv.ServerEnv,
+	owner OwnerLookup,
+) func(c *gin.Context) {
+
+	return func(c *gin.Context) {
+
+		ctx := c.Request.Context()
+		userID := ctxhelper.UserID(ctx)
+
+		if owner != nil {
+			ownerID, err := owner(c)
+			if err != nil {
+				logger.Errorf("could not look up owner for privilege %v: %v", privilege, err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "could not check privileges",
+				})
+				c.Abort()
+				return
+			}
+
+			if ownerID != nil && *ownerID == userID {
+				return
+			}
+		}
+
+		user, err := repos.NewUserDB(env.Database()).GetByID(ctx, userID)
+		if err != nil || user == nil {
+			logger.Errorf("could not get user %v for privilege %v: %v", userID, privilege, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": "could not check privileges",
+			})
+			c.Abort()
+			return
+		}
+
+		threshold := config.Threshold(privilege)
+		if user.Reputation < threshold {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success":   false,
+				"message":   fmt.Sprintf("you need the '%v' privilege, which requires %d reputation", privilege, threshold),
+				"privilege": privilege,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(privilegeKeyPrefix+string(privilege), true)
+	}
+}
+
+// PrivilegeGranted reports whether RequirePrivilege let the request through
+// on reputation rather than ownership.
+func PrivilegeGranted(c *gin.Context, privilege entities.Privilege) bool {
+	return c.GetBool(privilegeKeyPrefix + string(privilege))
+}
@@ -9,10 +9,15 @@ import (
 	"goquizbox/internal/serverenv"
 	"net/http"
 	"time"
+
+	null "gopkg.in/guregu/null.v4"
 )
 
 const (
 	tokenHeader = "X-Auth-Token"
+
+	// SessionLifetime is how long a session lasts without being refreshed.
+	SessionLifetime = 3 * time.Hour
 )
 
 var ErrTokenNotProvided = errors.New("token not provided")
@@ -29,14 +34,7 @@ type AppSessionAuthenticator struct {
 	env        *serverenv.ServerEnv
 }
 
-func NewSessionAuthenticator(env *serverenv.ServerEnv) SessionAuthenticator {
-	return NewSessionAuthenticatorWithJWTHandler(
-		NewJWTHandler(),
-		env,
-	)
-}
-
-func NewSessionAuthenticatorWithJWTHandler(
+func NewSessionAuthenticator(
 	jwtHandler JWTHandler,
 	env *serverenv.ServerEnv,
 ) SessionAuthenticator {
@@ -58,8 +56,13 @@ func (a *AppSessionAuthenticator) RefreshTokenFromRequest(
 		return "", fmt.Errorf("failed to find session by id=[%v]: %v", tokenInfo.SessionID, err)
 	}
 
-	if session.DeactivatedAt.Valid {
-		return "", fmt.Errorf("failed to refresh session by id=[%v], deactivated ", tokenInfo.SessionID)
+	if session == nil {
+		return "", fmt.Errorf("failed to find session by id=[%v]", tokenInfo.SessionID)
+	}
+
+	now := time.Now()
+	if !session.IsActive(now) {
+		return "", fmt.Errorf("failed to refresh session by id=[%v], deactivated or expired", tokenInfo.SessionID)
 	}
 
 	db := repos.NewUserDB(a.env.Database())
@@ -72,7 +75,9 @@ func (a *AppSessionAuthenticator) RefreshTokenFromRequest(
 		return "", fmt.Errorf("failed to refresh session by id=[%v] for user with id=[%v], user status inactive", tokenInfo.SessionID, session.UserID)
 	}
 
-	session.LastRefreshedAt = time.Now()
+	session.LastRefreshedAt = now
+	session.ExpiresAt = null.TimeFrom(now.Add(SessionLifetime))
+	session.UpdatedAt = null.TimeFrom(now)
 
 	err = sessionDB.Save(ctx, session)
 	if err != nil {
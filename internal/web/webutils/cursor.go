@@ -0,0 +1,77 @@
+package webutils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued by us or
+// that belongs to a listing in another order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type (
+	// Cursor marks a position in a listing by the sort key values of a row.
+	// Pages after it start with the next row, Before pages end with the row
+	// before it.
+	Cursor struct {
+		Sort   string   `json:"s"`
+		Values []string `json:"v"`
+		Before bool     `json:"b,omitempty"`
+	}
+
+	// CursorPage holds the cursors around a page, nil when there is no page
+	// in that direction.
+	CursorPage struct {
+		Next *Cursor
+		Prev *Cursor
+	}
+
+	// CursorCodec turns cursors into opaque tokens signed with HMAC-SHA256,
+	// so clients cannot craft their own.
+	CursorCodec struct {
+		key []byte
+	}
+)
+
+func NewCursorCodec(key []byte) *CursorCodec {
+	return &CursorCodec{key: key}
+}
+
+func (c *CursorCodec) Encode(cursor *Cursor) string {
+	// A Cursor always marshals.
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+func (c *CursorCodec) Decode(token string) (*Cursor, error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	if err := json.Unmarshal(payload, cursor); err != nil || cursor.Sort == "" || len(cursor.Values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
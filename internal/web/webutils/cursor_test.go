@@ -0,0 +1,38 @@
+package webutils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec([]byte("0123456789abcdef0123456789abcdef"))
+	cursor := &Cursor{Sort: "questions:newest:desc", Values: []string{"2023-01-02 10:00:00+00", "42"}, Before: true}
+
+	token := codec.Encode(cursor)
+	got, err := codec.Decode(token)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, cursor) {
+		t.Errorf("got %+v, want %+v", got, cursor)
+	}
+
+	payload, mac, _ := strings.Cut(token, ".")
+	forged := NewCursorCodec([]byte("another key")).Encode(cursor)
+	_, forgedMAC, _ := strings.Cut(forged, ".")
+
+	for _, bad := range []string{
+		"",
+		payload,
+		payload + "." + forgedMAC,
+		payload[1:] + "." + mac,
+		"e30." + mac,
+	} {
+		if _, err := codec.Decode(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q) = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
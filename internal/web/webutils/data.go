@@ -9,15 +9,22 @@ import (
 )
 
 type Filter struct {
-	Page     int
-	Per      int
-	From     string
-	To       string
-	Term     string
-	FromTime null.Time
-	ToTime   null.Time
-	UserID   null.Int
-	Deleted  null.Bool
+	Page         int
+	Per          int
+	From         string
+	To           string
+	Term         string
+	FromTime     null.Time
+	ToTime       null.Time
+	UserID       null.Int
+	Deleted      null.Bool
+	Tags         []string
+	MatchAllTags bool
+	// CursorMode is set by a 'cursor' query param, even an empty one, and
+	// pages by Cursor instead of Page.
+	CursorMode bool
+	RawCursor  string
+	Cursor     *Cursor
 }
 
 func (f *Filter) ConvertTime() error {
@@ -44,13 +51,15 @@ func (f *Filter) ConvertTime() error {
 
 func (f *Filter) NoPagination() *Filter {
 	return &Filter{
-		From:     f.From,
-		To:       f.To,
-		Term:     f.Term,
-		FromTime: f.FromTime,
-		ToTime:   f.ToTime,
-		UserID:   f.UserID,
-		Deleted:  f.Deleted,
+		From:         f.From,
+		To:           f.To,
+		Term:         f.Term,
+		FromTime:     f.FromTime,
+		ToTime:       f.ToTime,
+		UserID:       f.UserID,
+		Deleted:      f.Deleted,
+		Tags:         f.Tags,
+		MatchAllTags: f.MatchAllTags,
 	}
 }
 
@@ -98,6 +107,13 @@ type KeywordFilter struct {
 	ShortCodeID null.Int
 }
 
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// OrderFilter picks the sort key of a listing by name. An empty Order
+// keeps the direction the sort key defaults to.
 type OrderFilter struct {
 	Field string
 	Order string
@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"strings"
 
+	"goquizbox/internal/entities"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -33,9 +35,28 @@ func FilterFromContext(
 		filter.Deleted.SetValid(isDeleted)
 	}
 
+	filter.Tags = tagsFromContext(c)
+
+	filter.RawCursor, filter.CursorMode = c.GetQuery("cursor")
+	filter.RawCursor = strings.TrimSpace(filter.RawCursor)
+
+	switch tagMode := strings.TrimSpace(c.Query("tag_mode")); tagMode {
+	case "", "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		return filter, fmt.Errorf("invalid tag_mode query given [%v]", tagMode)
+	}
+
 	return filter, nil
 }
 
+// tagsFromContext collects the normalized 'tag' query params, which may be
+// repeated or comma separated.
+func tagsFromContext(c *gin.Context) []string {
+	return entities.ParseTags(strings.Join(c.QueryArray("tag"), ","))
+}
+
 func ApiKeyFilterFromContext(
 	c *gin.Context,
 ) (*ApiKeyFilter, error) {
@@ -65,19 +86,25 @@ func MessageFilterFromContext(
 	return messageFilter, nil
 }
 
+const (
+	DefaultPer = 20
+	// MaxPer caps the 'per' query param, larger values are cut down to it.
+	MaxPer = 100
+)
+
 func paginationFromContext(
 	c *gin.Context,
 ) (int, int, error) {
 
 	page := 1
-	per := 20
+	per := DefaultPer
 
 	var err error
 
 	pageQuery := strings.TrimSpace(c.Query("page"))
 	if pageQuery != "" {
 		page, err = strconv.Atoi(pageQuery)
-		if err != nil {
+		if err != nil || page < 1 {
 			return page, per, fmt.Errorf("invalid page query given [%v]", pageQuery)
 		}
 	}
@@ -85,11 +112,15 @@ func paginationFromContext(
 	perQuery := strings.TrimSpace(c.Query("per"))
 	if perQuery != "" {
 		per, err = strconv.Atoi(perQuery)
-		if err != nil {
+		if err != nil || per < 1 {
 			return page, per, fmt.Errorf("invalid per query given [%v]", perQuery)
 		}
 	}
 
+	if per > MaxPer {
+		per = MaxPer
+	}
+
 	return page, per, nil
 }
 
@@ -98,8 +129,14 @@ func OrderFilterFromContext(
 ) (*OrderFilter, error) {
 
 	filter := &OrderFilter{}
-	filter.Field = strings.TrimSpace(c.Query("order_by"))
-	filter.Order = strings.TrimSpace(c.Query("order"))
+	filter.Field = strings.ToLower(strings.TrimSpace(c.Query("order_by")))
+	filter.Order = strings.ToLower(strings.TrimSpace(c.Query("order")))
+
+	switch filter.Order {
+	case "", OrderAsc, OrderDesc:
+	default:
+		return filter, fmt.Errorf("invalid order query given [%v]", filter.Order)
+	}
 
 	return filter, nil
 }
@@ -0,0 +1,191 @@
+package webutils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"goquizbox/internal/entities"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+type (
+	// SearchQuery is a parsed search term. Words, phrases and exclusions are
+	// matched against the full-text index, the operators narrow results by
+	// tag, author, state and score.
+	SearchQuery struct {
+		Words    []string
+		Phrases  []string
+		Excluded []string
+		Tags     []string
+		UserID   null.Int
+		Answered null.Bool
+		Accepted null.Bool
+		Closed   null.Bool
+		Score    *ScoreFilter
+	}
+
+	// ScoreFilter compares the net vote score against Value with Op, one
+	// of =, >, >=, < or <=.
+	ScoreFilter struct {
+		Op    string
+		Value int
+	}
+)
+
+// ParseSearch reads the search syntax:
+//
+//	"exact phrase"  -exclude  -"excluded phrase"
+//	tag:go  user:42  is:answered  is:unanswered  is:accepted  is:closed  is:open
+//	score:>5  score:<=0  score:3 (same as score:>=3)
+//
+// Anything that is not valid syntax is searched for as a plain word.
+func ParseSearch(term string) *SearchQuery {
+	search := &SearchQuery{}
+
+	for _, token := range tokenizeSearch(term) {
+		if token.quoted {
+			if token.excluded {
+				search.Excluded = appendNonBlank(search.Excluded, token.text)
+			} else {
+				search.Phrases = appendNonBlank(search.Phrases, token.text)
+			}
+			continue
+		}
+
+		if token.excluded {
+			search.Excluded = appendNonBlank(search.Excluded, token.text)
+			continue
+		}
+
+		if !search.applyOperator(token.text) {
+			search.Words = appendNonBlank(search.Words, token.text)
+		}
+	}
+
+	return search
+}
+
+// HasText reports whether the query needs the full-text index.
+func (q *SearchQuery) HasText() bool {
+	return len(q.Words) > 0 || len(q.Phrases) > 0 || len(q.Excluded) > 0
+}
+
+func (q *SearchQuery) applyOperator(token string) bool {
+	key, value, ok := strings.Cut(token, ":")
+	if !ok || value == "" {
+		return false
+	}
+
+	switch strings.ToLower(key) {
+	case "tag":
+		tags := entities.ParseTags(value)
+		if len(tags) == 0 {
+			return false
+		}
+		q.Tags = append(q.Tags, tags...)
+	case "user":
+		userID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || userID < 1 {
+			return false
+		}
+		q.UserID = null.IntFrom(userID)
+	case "is":
+		switch strings.ToLower(value) {
+		case "answered":
+			q.Answered = null.BoolFrom(true)
+		case "unanswered":
+			q.Answered = null.BoolFrom(false)
+		case "accepted", "resolved":
+			q.Accepted = null.BoolFrom(true)
+		case "closed":
+			q.Closed = null.BoolFrom(true)
+		case "open":
+			q.Closed = null.BoolFrom(false)
+		default:
+			return false
+		}
+	case "score":
+		score, ok := parseScoreFilter(value)
+		if !ok {
+			return false
+		}
+		q.Score = score
+	default:
+		return false
+	}
+
+	return true
+}
+
+func parseScoreFilter(value string) (*ScoreFilter, bool) {
+	op := ">="
+	for _, prefix := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, prefix) {
+			op = prefix
+			value = value[len(prefix):]
+			break
+		}
+	}
+
+	score, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, false
+	}
+	return &ScoreFilter{Op: op, Value: score}, true
+}
+
+type searchToken struct {
+	text     string
+	quoted   bool
+	excluded bool
+}
+
+// tokenizeSearch splits term on whitespace, keeping quoted phrases whole. An
+// unterminated quote runs to the end of the term.
+func tokenizeSearch(term string) []searchToken {
+	tokens := make([]searchToken, 0)
+	runes := []rune(term)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		token := searchToken{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.excluded = true
+			i++
+		}
+
+		if runes[i] == '"' {
+			token.quoted = true
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			token.text = string(runes[i+1 : end])
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			token.text = string(runes[i:end])
+			i = end
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+func appendNonBlank(values []string, value string) []string {
+	if value = strings.TrimSpace(value); value != "" {
+		values = append(values, value)
+	}
+	return values
+}
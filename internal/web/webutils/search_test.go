@@ -0,0 +1,63 @@
+package webutils
+
+import (
+	"reflect"
+	"testing"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		term string
+		want *SearchQuery
+	}{
+		{
+			term: `  goroutine   leaks `,
+			want: &SearchQuery{Words: []string{"goroutine", "leaks"}},
+		},
+		{
+			term: `"race condition" -mutex -"data race" channels`,
+			want: &SearchQuery{
+				Words:    []string{"channels"},
+				Phrases:  []string{"race condition"},
+				Excluded: []string{"mutex", "data race"},
+			},
+		},
+		{
+			term: `tag:Go tag:postgres user:42 is:answered score:>5`,
+			want: &SearchQuery{
+				Tags:     []string{"go", "postgres"},
+				UserID:   null.IntFrom(42),
+				Answered: null.BoolFrom(true),
+				Score:    &ScoreFilter{Op: ">", Value: 5},
+			},
+		},
+		{
+			term: `is:unanswered is:closed is:accepted score:-1`,
+			want: &SearchQuery{
+				Answered: null.BoolFrom(false),
+				Accepted: null.BoolFrom(true),
+				Closed:   null.BoolFrom(true),
+				Score:    &ScoreFilter{Op: ">=", Value: -1},
+			},
+		},
+		{
+			term: `user:me is:weird score:>x http://example.com "tag:go`,
+			want: &SearchQuery{
+				Words:   []string{"user:me", "is:weird", "score:>x", "http://example.com"},
+				Phrases: []string{"tag:go"},
+			},
+		},
+		{
+			term: `- "" -`,
+			want: &SearchQuery{Words: []string{"-", "-"}},
+		},
+	}
+
+	for _, tc := range tests {
+		if got := ParseSearch(tc.term); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseSearch(%q) = %+v, want %+v", tc.term, got, tc.want)
+		}
+	}
+}
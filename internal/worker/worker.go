@@ -0,0 +1,34 @@
+// Package worker runs periodic background jobs such as the badge sweep.
+package worker
+
+import (
+	"context"
+	"time"
+
+	"goquizbox/internal/logger"
+)
+
+// Every calls job immediately and then every interval until ctx is done.
+// Failures are logged with name and do not stop the loop. A non-positive
+// interval is a configuration error, so it is logged and nothing runs.
+func Every(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	if interval <= 0 {
+		logger.Errorf("not running %v: interval must be positive, got %v", name, interval)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			logger.Errorf("failed to %v: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goquizbox/internal/logger"
+)
+
+func TestEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Every(ctx, "count", time.Millisecond, func(context.Context) error {
+			runs++
+			if runs == 3 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Every did not stop when ctx was done")
+	}
+	if runs != 3 {
+		t.Errorf("got %d runs, want 3", runs)
+	}
+}
+
+func TestEveryRejectsNonPositiveInterval(t *testing.T) {
+	logger.MustInit()
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ran := false
+		Every(context.Background(), "noop", interval, func(context.Context) error {
+			ran = true
+			return nil
+		})
+		if ran {
+			t.Errorf("expected interval %v not to run the job", interval)
+		}
+	}
+}
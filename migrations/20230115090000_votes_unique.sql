@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+delete from votes v using votes d
+  where v.user_id = d.user_id and v.kind_id = d.kind_id and v.kind = d.kind and v.id < d.id;
+
+alter table votes alter column kind_id set not null;
+
+create unique index votes_user_kind_uniq_idx ON votes(user_id, kind_id, kind);
+
+create index votes_kind_idx ON votes(kind, kind_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists votes_kind_idx;
+
+drop index if exists votes_user_kind_uniq_idx;
+
+alter table votes alter column kind_id drop not null;
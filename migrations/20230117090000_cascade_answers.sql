@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+alter table answers drop constraint if exists answers_question_id_fkey;
+
+alter table answers add constraint answers_question_id_fkey
+  foreign key (question_id) references questions(id) on delete cascade;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+alter table answers drop constraint if exists answers_question_id_fkey;
+
+alter table answers add constraint answers_question_id_fkey
+  foreign key (question_id) references questions(id);
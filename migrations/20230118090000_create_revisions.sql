@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create type post_type as enum ('question', 'answer');
+
+create table revisions (
+  id bigserial primary key,
+  kind post_type not null,
+  kind_id bigint not null,
+  revision int not null,
+  user_id bigint references users(id),
+  title varchar(255),
+  body text,
+  tags varchar(255),
+  summary varchar(255),
+  created_at timestamptz not null default clock_timestamp(),
+  updated_at timestamptz
+);
+
+create unique index revisions_kind_revision_uniq_idx ON revisions(kind, kind_id, revision);
+
+insert into revisions (kind, kind_id, revision, user_id, title, body, tags, created_at)
+  select 'question', id, 1, user_id, title, body, tags, coalesce(updated_at, created_at) from questions;
+
+insert into revisions (kind, kind_id, revision, user_id, body, created_at)
+  select 'answer', id, 1, user_id, body, coalesce(updated_at, created_at) from answers;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists revisions_kind_revision_uniq_idx;
+
+drop table if exists revisions;
+
+drop type if exists post_type;
@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table tags (
+  id bigserial primary key,
+  name varchar(35) not null,
+  created_at timestamptz not null default clock_timestamp(),
+  updated_at timestamptz
+);
+
+create unique index tags_name_uniq_idx ON tags(name);
+
+create table question_tags (
+  question_id bigint not null references questions(id) on delete cascade,
+  tag_id bigint not null references tags(id) on delete cascade,
+  primary key (question_id, tag_id)
+);
+
+create index question_tags_tag_idx ON question_tags(tag_id);
+
+create temporary table legacy_question_tags on commit drop as
+  select distinct id as question_id, left(
+    regexp_replace(regexp_replace(lower(trim(tag)), '\s+', '-', 'g'), '[^a-z0-9+#.\-]', '', 'g'), 35
+  ) as name
+  from questions, regexp_split_to_table(tags, ',') as tag;
+
+delete from legacy_question_tags where name = '';
+
+insert into tags (name) select distinct name from legacy_question_tags;
+
+insert into question_tags (question_id, tag_id)
+  select l.question_id, t.id from legacy_question_tags l join tags t on t.name = l.name;
+
+alter table questions drop column tags;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+alter table questions add column tags varchar(255) not null default '';
+
+update questions q set tags = coalesce((
+  select string_agg(t.name, ',' order by t.name) from question_tags qt join tags t on t.id = qt.tag_id
+  where qt.question_id = q.id
+), '');
+
+drop index if exists question_tags_tag_idx;
+
+drop table if exists question_tags;
+
+drop index if exists tags_name_uniq_idx;
+
+drop table if exists tags;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table comments (
+  id bigserial primary key,
+  kind post_type not null,
+  kind_id bigint not null,
+  user_id bigint references users(id),
+  parent_id bigint references comments(id) on delete cascade,
+  body varchar(600) not null,
+  created_at timestamptz not null default clock_timestamp(),
+  updated_at timestamptz
+);
+
+create index comments_kind_idx ON comments(kind, kind_id);
+
+create index comments_parent_idx ON comments(parent_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists comments_parent_idx;
+
+drop index if exists comments_kind_idx;
+
+drop table if exists comments;
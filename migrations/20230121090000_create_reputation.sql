@@ -0,0 +1,66 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table reputation_events (
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  amount integer not null,
+  reason varchar(50) not null,
+  kind post_type not null,
+  kind_id bigint not null,
+  actor_id bigint references users(id) on delete set null,
+  revocation boolean not null default false,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create index reputation_events_user_idx ON reputation_events(user_id, id);
+
+create index reputation_events_kind_idx ON reputation_events(kind, kind_id);
+
+alter table users add column reputation integer not null default 1;
+
+insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id)
+  select p.user_id, case when v.mode = 'up' then 10 else -2 end,
+    case when v.mode = 'up' then v.kind::text || '_upvoted' else 'post_downvoted' end,
+    v.kind::text::post_type, v.kind_id, v.user_id
+  from votes v
+  join (
+    select 'question' as kind, id, user_id from questions
+    union all
+    select 'answer' as kind, id, user_id from answers
+  ) p on p.kind = v.kind::text and p.id = v.kind_id
+  where p.user_id is not null and p.user_id <> v.user_id;
+
+insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id)
+  select v.user_id, -1, 'answer_downvote_cast', 'answer', v.kind_id, v.user_id
+  from votes v join answers a on a.id = v.kind_id
+  where v.kind = 'answer' and v.mode = 'down'
+    and a.user_id is not null and a.user_id <> v.user_id;
+
+insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id)
+  select a.user_id, 15, 'answer_accepted', 'answer', a.id, q.user_id
+  from questions q join answers a on a.id = q.accepted_answer_id
+  where a.user_id is not null and a.user_id <> q.user_id;
+
+insert into reputation_events (user_id, amount, reason, kind, kind_id, actor_id)
+  select q.user_id, 2, 'answer_accept_cast', 'answer', a.id, q.user_id
+  from questions q join answers a on a.id = q.accepted_answer_id
+  where q.user_id is not null and a.user_id <> q.user_id;
+
+update users set reputation = greatest(1, 1 + coalesce(
+  (select sum(amount) from reputation_events where user_id = users.id), 0));
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+alter table users drop column if exists reputation;
+
+drop index if exists reputation_events_kind_idx;
+
+drop index if exists reputation_events_user_idx;
+
+drop table if exists reputation_events;
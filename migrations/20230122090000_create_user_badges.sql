@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table user_badges (
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  badge varchar(50) not null,
+  context varchar(100) not null default '',
+  awarded_at timestamptz not null default clock_timestamp()
+);
+
+create unique index user_badges_uniq_idx ON user_badges(user_id, badge, context);
+
+create index user_badges_badge_idx ON user_badges(badge, awarded_at);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists user_badges_badge_idx;
+
+drop index if exists user_badges_uniq_idx;
+
+drop table if exists user_badges;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create type close_reason as enum ('duplicate', 'off_topic', 'needs_details', 'opinion_based');
+create type close_vote_type as enum ('close', 'reopen');
+
+alter table questions add column closed_at timestamptz;
+alter table questions add column close_reason close_reason;
+alter table questions add column duplicate_of_id bigint references questions(id) on delete set null;
+
+create table close_votes (
+  id bigserial primary key,
+  question_id bigint not null references questions(id) on delete cascade,
+  user_id bigint not null references users(id) on delete cascade,
+  kind close_vote_type not null,
+  reason close_reason,
+  duplicate_of_id bigint references questions(id) on delete set null,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create unique index close_votes_uniq_idx ON close_votes(question_id, user_id, kind);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists close_votes_uniq_idx;
+
+drop table if exists close_votes;
+
+alter table questions drop column if exists duplicate_of_id;
+alter table questions drop column if exists close_reason;
+alter table questions drop column if exists closed_at;
+
+drop type if exists close_vote_type;
+drop type if exists close_reason;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table password_resets (
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  token_hash varchar(64) not null,
+  ip_address varchar(255) not null,
+  expires_at timestamptz not null,
+  used_at timestamptz,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create unique index password_resets_token_uniq_idx ON password_resets(token_hash);
+
+create index password_resets_user_idx ON password_resets(user_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists password_resets_user_idx;
+
+drop index if exists password_resets_token_uniq_idx;
+
+drop table if exists password_resets;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create type api_key_scope as enum ('read', 'write');
+
+create table api_keys (
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  name varchar(100) not null,
+  username varchar(40) not null,
+  secret_hash varchar(255) not null,
+  scope api_key_scope not null default 'read',
+  last_used_at timestamptz,
+  revoked_at timestamptz,
+  created_at timestamptz not null default clock_timestamp(),
+  updated_at timestamptz
+);
+
+create unique index api_keys_username_uniq_idx ON api_keys(username);
+
+create index api_keys_user_idx ON api_keys(user_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists api_keys_user_idx;
+
+drop index if exists api_keys_username_uniq_idx;
+
+drop table if exists api_keys;
+
+drop type if exists api_key_scope;
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+alter table users add column totp_secret varchar(64);
+alter table users add column totp_enabled_at timestamptz;
+alter table users add column totp_last_step bigint;
+
+create table recovery_codes (
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  code_hash varchar(64) not null,
+  used_at timestamptz,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create unique index recovery_codes_user_code_uniq_idx ON recovery_codes(user_id, code_hash);
+
+create table login_challenges (
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  token_hash varchar(64) not null,
+  attempts int not null default 0,
+  expires_at timestamptz not null,
+  used_at timestamptz,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create unique index login_challenges_token_uniq_idx ON login_challenges(token_hash);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists login_challenges_token_uniq_idx;
+
+drop table if exists login_challenges;
+
+drop index if exists recovery_codes_user_code_uniq_idx;
+
+drop table if exists recovery_codes;
+
+alter table users drop column if exists totp_last_step;
+alter table users drop column if exists totp_enabled_at;
+alter table users drop column if exists totp_secret;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table oidc_logins (
+  id bigserial primary key,
+  provider varchar(64) not null,
+  state_hash varchar(64) not null,
+  nonce varchar(64) not null,
+  code_verifier varchar(64) not null,
+  expires_at timestamptz not null,
+  used_at timestamptz,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create unique index oidc_logins_state_uniq_idx ON oidc_logins(state_hash);
+
+create table user_identities (
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  provider varchar(64) not null,
+  subject varchar(255) not null,
+  email varchar(255) not null,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create unique index user_identities_provider_subject_uniq_idx ON user_identities(provider, subject);
+
+create index user_identities_user_idx ON user_identities(user_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists user_identities_user_idx;
+
+drop index if exists user_identities_provider_subject_uniq_idx;
+
+drop table if exists user_identities;
+
+drop index if exists oidc_logins_state_uniq_idx;
+
+drop table if exists oidc_logins;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create type user_role as enum ('user', 'moderator', 'admin');
+
+alter table users add column role user_role not null default 'user';
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+alter table users drop column if exists role;
+
+drop type if exists user_role;
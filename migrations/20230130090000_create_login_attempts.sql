@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table login_attempts (
+  id bigserial primary key,
+  email varchar(255) not null,
+  ip_address varchar(255) not null,
+  success boolean not null default false,
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create index login_attempts_email_idx ON login_attempts(email, created_at);
+
+create index login_attempts_ip_idx ON login_attempts(ip_address, created_at);
+
+create table audit_logs (
+  id bigserial primary key,
+  user_id bigint references users(id) on delete set null,
+  action varchar(64) not null,
+  ip_address varchar(255) not null,
+  user_agent varchar(255) not null,
+  details jsonb not null default '{}',
+  created_at timestamptz not null default clock_timestamp()
+);
+
+create index audit_logs_user_idx ON audit_logs(user_id, created_at);
+
+create index audit_logs_action_idx ON audit_logs(action, created_at);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists audit_logs_action_idx;
+
+drop index if exists audit_logs_user_idx;
+
+drop table if exists audit_logs;
+
+drop index if exists login_attempts_ip_idx;
+
+drop index if exists login_attempts_email_idx;
+
+drop table if exists login_attempts;
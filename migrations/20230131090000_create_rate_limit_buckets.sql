@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+create table rate_limit_buckets (
+  key varchar(255) primary key,
+  tokens double precision not null,
+  updated_at timestamptz not null
+);
+
+create index rate_limit_buckets_updated_idx ON rate_limit_buckets(updated_at);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists rate_limit_buckets_updated_idx;
+
+drop table if exists rate_limit_buckets;
@@ -0,0 +1,97 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+alter table questions add column search_vector tsvector;
+
+alter table answers add column search_vector tsvector;
+
+-- +goose StatementBegin
+create or replace function question_search_vector(q_id bigint, q_title text, q_body text) returns tsvector as $$
+  select setweight(to_tsvector('english', coalesce(q_title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(q_body, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce((
+      select string_agg(t.name, ' ') from question_tags qt join tags t on t.id = qt.tag_id where qt.question_id = q_id
+    ), '')), 'C');
+$$ language sql stable;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create or replace function questions_search_vector_update() returns trigger as $$
+begin
+  new.search_vector := question_search_vector(new.id, new.title, new.body);
+  return new;
+end
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger questions_search_vector_trg before insert or update of title, body on questions
+  for each row execute procedure questions_search_vector_update();
+
+-- +goose StatementBegin
+create or replace function question_tags_search_vector_update() returns trigger as $$
+declare
+  changed_id bigint;
+begin
+  if tg_op = 'DELETE' then
+    changed_id := old.question_id;
+  else
+    changed_id := new.question_id;
+  end if;
+
+  update questions set search_vector = question_search_vector(id, title, body) where id = changed_id;
+  return null;
+end
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger question_tags_search_vector_trg after insert or delete on question_tags
+  for each row execute procedure question_tags_search_vector_update();
+
+-- +goose StatementBegin
+create or replace function answers_search_vector_update() returns trigger as $$
+begin
+  new.search_vector := to_tsvector('english', coalesce(new.body, ''));
+  return new;
+end
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger answers_search_vector_trg before insert or update of body on answers
+  for each row execute procedure answers_search_vector_update();
+
+update questions set search_vector = question_search_vector(id, title, body);
+
+update answers set search_vector = to_tsvector('english', coalesce(body, ''));
+
+create index questions_search_idx ON questions using gin(search_vector);
+
+create index answers_search_idx ON answers using gin(search_vector);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists answers_search_idx;
+
+drop index if exists questions_search_idx;
+
+drop trigger if exists answers_search_vector_trg on answers;
+
+drop function if exists answers_search_vector_update();
+
+drop trigger if exists question_tags_search_vector_trg on question_tags;
+
+drop function if exists question_tags_search_vector_update();
+
+drop trigger if exists questions_search_vector_trg on questions;
+
+drop function if exists questions_search_vector_update();
+
+drop function if exists question_search_vector(bigint, text, text);
+
+alter table answers drop column if exists search_vector;
+
+alter table questions drop column if exists search_vector;
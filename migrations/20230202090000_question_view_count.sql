@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+alter table questions add column view_count bigint not null default 0;
+
+create index questions_created_idx ON questions(created_at);
+
+create index questions_view_count_idx ON questions(view_count);
+
+create index users_reputation_idx ON users(reputation);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists users_reputation_idx;
+
+drop index if exists questions_view_count_idx;
+
+drop index if exists questions_created_idx;
+
+alter table questions drop column if exists view_count;
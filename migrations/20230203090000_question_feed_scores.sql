@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+alter table questions add column hot_score double precision not null default 0;
+
+alter table questions add column trending_score double precision not null default 0;
+
+alter table questions add column scored_at timestamptz;
+
+create index questions_hot_score_idx ON questions(hot_score desc, id desc);
+
+create index questions_trending_score_idx ON questions(trending_score desc, id desc) where trending_score > 0;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+drop index if exists questions_trending_score_idx;
+
+drop index if exists questions_hot_score_idx;
+
+alter table questions drop column if exists scored_at;
+
+alter table questions drop column if exists trending_score;
+
+alter table questions drop column if exists hot_score;